@@ -0,0 +1,125 @@
+package WesternElectric
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// Test_attribute shows the same proportion of failures is anomalous in a
+// large sample, but not in a small one, where it's more likely by chance.
+func Test_attribute(t *testing.T) {
+	const failures = "1 5 100\n2 5 100\n3 5 100\n4 5 100\n5 5 100\n6 5 100\n7 5 100\n8 5 100\n9 5 100\n10 5 100\n"
+	rules, _ := RuleSet("ThreeSigma")
+	tests := []struct {
+		last  string
+		size  float64
+		rules int
+	}{
+		{last: "11 6 50", size: 50},
+		{last: "11 12 100", size: 100, rules: 3},
+	}
+	for _, tt := range tests {
+		res, err := Analyze(strings.NewReader(failures+tt.last), Options{NSamples: 10, Attribute: "p", Rules: rules})
+		if err != nil {
+			t.Fatalf("Analyze() returned %v", err)
+		}
+		if len(res.Events) != 1 {
+			t.Fatalf("Analyze() returned %d events, expected 1", len(res.Events))
+		}
+		e := res.Events[0]
+		if e.Datum != 0.12 || e.Mean != 0.05 || math.Abs(e.SD-math.Sqrt(0.05*0.95/tt.size)) > 1e-12 {
+			t.Errorf("%s: %g against %g, sd %g, expected 0.12 against 0.05, for a sample of %g", e.Date, e.Datum, e.Mean, e.SD, tt.size)
+		}
+		if rc := e.Indicator("ThreeSigma"); rc != tt.rules {
+			t.Errorf("%s: ThreeSigma = %d, expected %d", tt.last, rc, tt.rules)
+		}
+	}
+}
+
+func Test_attributeKinds(t *testing.T) {
+	const counts = "1 4\n2 3\n3 5\n4 4\n5 15\n"
+	rules, _ := RuleSet("ThreeSigma")
+	tests := []struct {
+		name    string
+		opts    Options
+		value   float64 // of the last count
+		sd      float64
+		skipped int
+		fail    bool
+	}{
+		{name: "c", opts: Options{Attribute: "c"}, value: 15, sd: 2},
+		{name: "u", opts: Options{Attribute: "u", SampleSize: 4}, value: 3.75, sd: 0.5},
+		{name: "np", opts: Options{Attribute: "np", SampleSize: 100}, value: 15, sd: math.Sqrt(100 * 0.04 * 0.96)},
+		{name: "p, no sizes", opts: Options{Attribute: "p"}, skipped: 5},
+		{name: "unknown", opts: Options{Attribute: "x"}, fail: true},
+		{name: "fixed limits", opts: Options{Attribute: "c", PhaseI: 3}, fail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.NSamples, tt.opts.Rules, tt.opts.Malformed = 4, rules, PolicyCount
+			res, err := Analyze(strings.NewReader(counts), tt.opts)
+			switch {
+			case tt.fail:
+				if err == nil {
+					t.Errorf("Analyze() returned no error")
+				}
+				return
+			case err != nil:
+				t.Fatalf("Analyze() returned %v", err)
+			case res.Skipped != tt.skipped:
+				t.Errorf("Analyze() skipped %d lines, expected %d", res.Skipped, tt.skipped)
+			}
+			if tt.skipped != 0 {
+				return
+			}
+			e := res.Events[len(res.Events)-1]
+			if e.Datum != tt.value || math.Abs(e.SD-tt.sd) > 1e-12 || e.Indicator("ThreeSigma") != 3 {
+				t.Errorf("last count %g, sd %g, %v, expected %g, sd %g, ThreeSigma 3", e.Datum, e.SD, e.Signals, tt.value, tt.sd)
+			}
+		})
+	}
+}
+
+// Test_impossibleCounts skips negative counts, and more failures than
+// there were samples, rather than letting them spoil the centre line.
+func Test_impossibleCounts(t *testing.T) {
+	const counts = "1 5 100\n2 -1 100\n3 200 100\n4 5 100\n5 5 100\n"
+	tests := []struct {
+		kind    string
+		skipped int
+	}{
+		{kind: "p", skipped: 2},
+		{kind: "np", skipped: 2},
+		{kind: "u", skipped: 1}, // there can be more defects than units
+		{kind: "c", skipped: 1},
+	}
+	for _, tt := range tests {
+		res, err := Analyze(strings.NewReader(counts), Options{NSamples: 2, Attribute: tt.kind, Malformed: PolicyCount})
+		if err != nil {
+			t.Fatalf("%s: Analyze() returned %v", tt.kind, err)
+		}
+		if res.Skipped != tt.skipped {
+			t.Errorf("%s: Analyze() skipped %d lines, expected %d", tt.kind, res.Skipped, tt.skipped)
+		}
+	}
+}
+
+// Test_sizeColumn reads the sample sizes from a column that isn't just
+// after the counts.
+func Test_sizeColumn(t *testing.T) {
+	const data = "#time failed region requests\n1 5 east 100\n2 5 west 100\n3 5 east 100\n4 5 west 100\n5 12 east 100\n"
+	rules, _ := RuleSet("ThreeSigma")
+
+	res, err := Analyze(strings.NewReader(data), Options{NSamples: 4, Attribute: "p", Rules: rules,
+		ValueColumn: "failed", SizeColumn: "requests"})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	if len(res.Events) != 1 || res.Events[0].Datum != 0.12 || res.Events[0].Indicator("ThreeSigma") != 3 {
+		t.Errorf("Analyze() returned %+v, expected one event, 0.12 with ThreeSigma 3", res.Events)
+	}
+	if _, err := Analyze(strings.NewReader(data), Options{NSamples: 4, Attribute: "p", SizeColumn: "n"}); err == nil {
+		t.Errorf("Analyze() returned no error for an unknown size column")
+	}
+}
@@ -0,0 +1,174 @@
+package WesternElectric
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// aggregates reduce the data in a bucket to a single value. The
+// percentiles are nearest-rank, so are always one of the data.
+var aggregates = map[string]func(data []float64) float64{
+	"mean": func(data []float64) float64 {
+		return sum(data) / float64(len(data))
+	},
+	"sum": sum,
+	"count": func(data []float64) float64 {
+		return float64(len(data))
+	},
+	"max": func(data []float64) float64 {
+		return percentile(data, 100)
+	},
+	"p50": func(data []float64) float64 {
+		return percentile(data, 50)
+	},
+	"p95": func(data []float64) float64 {
+		return percentile(data, 95)
+	},
+	"p99": func(data []float64) float64 {
+		return percentile(data, 99)
+	},
+}
+
+// sum adds up the data.
+func sum(data []float64) float64 {
+	var total float64
+
+	for _, x := range data {
+		total += x
+	}
+	return total
+}
+
+// percentile returns the nearest-rank percentile of the data, which
+// it sorts.
+func percentile(data []float64, p float64) float64 {
+	sort.Float64s(data)
+	i := int(math.Ceil(p/100*float64(len(data)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return data[i]
+}
+
+// bucketer groups consecutive samples into fixed intervals of time,
+// and reduces each bucket to a single sample, at the start of the
+// interval, with an aggregate such as the mean or p99. Each series has
+// its own buckets, if the input has several.
+type bucketer struct {
+	width     time.Duration
+	aggregate func(data []float64) float64
+	format    func(t time.Time) string // for the datestamps of the buckets
+	grouped   bool                     // keep the data of each bucket, to make a subgroup of
+	buckets   map[string]*bucket       // by key
+	keys      []string                 // in the order they were first seen
+	done      []sample                 // the last buckets, at the end of the input
+	err       error                    // that ended the input, once we've seen it
+}
+
+// bucket is the bucket being filled, for one series.
+type bucket struct {
+	start time.Time
+	data  []float64
+	sizes []float64 // the sample sizes, for attribute charts
+}
+
+// newBucketer creates a bucketer for the buckets the options ask for,
+// or returns nil if they don't.
+func newBucketer(opts Options, format func(t time.Time) string) (*bucketer, error) {
+	if opts.Bucket == 0 {
+		return nil, nil
+	}
+	if opts.Bucket < 0 {
+		return nil, fmt.Errorf("bucket must be > 0, not %s", opts.Bucket)
+	}
+	name := opts.Aggregate
+	if name == "" {
+		name = "mean"
+	}
+	aggregate, ok := aggregates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregate %q, expected mean, sum, count, p50, p95, p99 or max", name)
+	}
+	if needsSizes(opts) && name != "sum" && name != "mean" {
+		// the sizes are reduced like the counts, and only these keep them in step
+		return nil, fmt.Errorf("the %s chart's counts and sample sizes can only be bucketed with sum or mean, not %s",
+			opts.Attribute, name)
+	}
+	return &bucketer{width: opts.Bucket, aggregate: aggregate, format: format,
+		grouped: opts.Subgroup != 0, buckets: make(map[string]*bucket)}, nil
+}
+
+// next reads samples until one falls in a new bucket of its series,
+// and returns the one it finishes. At the end of the input, it returns
+// the last bucket of each series, then the error that ended it.
+func (b *bucketer) next(read func() (sample, error)) (sample, error) {
+	for {
+		if len(b.done) > 0 {
+			s := b.done[0]
+			b.done = b.done[1:]
+			return s, nil
+		}
+		if b.err != nil {
+			return sample{}, b.err
+		}
+		s, err := read()
+		if err != nil {
+			b.err = err
+			for _, key := range b.keys {
+				if bk := b.buckets[key]; len(bk.data) > 0 {
+					b.done = append(b.done, b.flush(key, bk))
+				}
+			}
+			continue
+		}
+		bk := b.buckets[s.key]
+		if bk == nil {
+			bk = &bucket{}
+			b.buckets[s.key] = bk
+			b.keys = append(b.keys, s.key)
+		}
+		start := truncate(s.at, b.width)
+		if len(bk.data) > 0 && !start.Equal(bk.start) {
+			full := b.flush(s.key, bk)
+			bk.add(start, s)
+			return full, nil
+		}
+		bk.add(start, s)
+	}
+}
+
+// truncate finds the start of the bucket a time falls in, on the clock
+// of its own time zone, so hour buckets start on the hour and day ones at
+// midnight, even in zones that are a half hour or so from UTC. Buckets
+// shorter than a day start afresh each midnight, and longer ones count
+// days from the start of the calendar, as Time.Truncate does.
+func truncate(t time.Time, width time.Duration) time.Time {
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	if width < 24*time.Hour {
+		return midnight.Add(t.Sub(midnight).Truncate(width))
+	}
+	y, m, d = time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Truncate(width).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// add adds a sample to the bucket, which starts at start.
+func (bk *bucket) add(start time.Time, s sample) {
+	bk.start = start
+	bk.data = append(bk.data, s.datum)
+	bk.sizes = append(bk.sizes, s.size)
+}
+
+// flush reduces a series' bucket to a sample, and empties it.
+func (b *bucketer) flush(key string, bk *bucket) sample {
+	s := sample{key: key, date: b.format(bk.start), at: bk.start, size: b.aggregate(bk.sizes)}
+	if b.grouped {
+		// in the order they came, before the aggregate sorts them
+		s.group = append([]float64(nil), bk.data...)
+	}
+	s.datum = b.aggregate(bk.data)
+	bk.data, bk.sizes = bk.data[:0], bk.sizes[:0]
+	return s
+}
@@ -0,0 +1,179 @@
+package WesternElectric
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_aggregates(t *testing.T) {
+	data := []float64{7, 1, 3, 5, 9, 2, 4, 6, 8, 10}
+	tests := []struct {
+		name   string
+		expect float64
+	}{
+		{name: "mean", expect: 5.5},
+		{name: "sum", expect: 55},
+		{name: "count", expect: 10},
+		{name: "p50", expect: 5},
+		{name: "p95", expect: 10},
+		{name: "p99", expect: 10},
+		{name: "max", expect: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := append([]float64(nil), data...)
+			if got := aggregates[tt.name](in); got != tt.expect {
+				t.Errorf("%s = %g, expected %g", tt.name, got, tt.expect)
+			}
+		})
+	}
+	if got := percentile([]float64{3, 1, 2}, 1); got != 1 {
+		t.Errorf("p1 of 3 = %g, expected the smallest, 1", got)
+	}
+}
+
+// Test_buckets turns a feed of latencies, a few a minute, into ten-minute
+// samples, and finds the ten minutes with one very slow request in them.
+func Test_buckets(t *testing.T) {
+	var sb strings.Builder
+
+	start := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 8*60; i += 2 {
+		latency := 100 + i%7
+		if i == 7*60+32 {
+			latency = 5000
+		}
+		fmt.Fprintf(&sb, "%d %d\n", start.Add(time.Duration(i)*time.Minute).Unix(), latency)
+	}
+	rules, _ := RuleSet("ThreeSigma")
+	opts := Options{NSamples: 5, Rules: rules, Bucket: 10 * time.Minute}
+
+	opts.Aggregate = "mean"
+	res, err := Analyze(strings.NewReader(sb.String()), opts)
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	// 48 ten-minute buckets, the first five filling the average
+	if len(res.Events) != 43 || res.Read != 240 {
+		t.Fatalf("Analyze() returned %d events from %d lines, expected 43 from 240", len(res.Events), res.Read)
+	}
+	slow := res.Events[len(res.Events)-3]
+	if expect := start.Add(7*time.Hour + 30*time.Minute); !slow.Time.Equal(expect) {
+		t.Errorf("slow bucket started at %s, expected 17:30", slow.Time.Format("15:04"))
+	}
+	if slow.Date != fmt.Sprint(slow.Time.Unix()) {
+		t.Errorf("slow bucket's datestamp was %q, expected it as Unix seconds, like the input", slow.Date)
+	}
+	if slow.Indicator("ThreeSigma") != 3 {
+		t.Errorf("slow bucket's mean of %g was %v, expected ThreeSigma 3", slow.Datum, slow.Signals)
+	}
+
+	// the count is the same in every bucket
+	opts.Aggregate = "count"
+	res, _ = Analyze(strings.NewReader(sb.String()), opts)
+	if res.Events[0].Datum != 5 || res.Last != 0 {
+		t.Errorf("Analyze() counted %g, last = %d, expected 5 a bucket, and no anomalies", res.Events[0].Datum, res.Last)
+	}
+
+	for _, opts := range []Options{
+		{NSamples: 5, Bucket: -time.Minute},
+		{NSamples: 5, Bucket: time.Minute, Aggregate: "p90"},
+	} {
+		if _, err := Analyze(strings.NewReader(sb.String()), opts); err == nil {
+			t.Errorf("Analyze() returned no error for bucket %s, aggregate %q", opts.Bucket, opts.Aggregate)
+		}
+	}
+}
+
+// Test_bucketDates keeps the datestamps of buckets in the input's layout.
+func Test_bucketDates(t *testing.T) {
+	const data = "10:01 1\n10:04 3\n10:12 5\n10:25 7\n10:26 9\n"
+
+	in, err := newInput(strings.NewReader(data), Options{Bucket: 10 * time.Minute, Aggregate: "sum"})
+	if err != nil {
+		t.Fatalf("newInput() returned %v", err)
+	}
+	for _, expect := range []sample{{date: "10:00", datum: 4}, {date: "10:10", datum: 5}, {date: "10:20", datum: 16}} {
+		s, err := in.next()
+		if err != nil || s.date != expect.date || s.datum != expect.datum {
+			t.Errorf("next() = %q %g, %v, expected %q %g", s.date, s.datum, err, expect.date, expect.datum)
+		}
+	}
+}
+
+// Test_bucketZones starts buckets on the clock of the time zone, not of
+// UTC, for zones a half hour off it, days that start at 19:00 UTC, and
+// times without dates.
+func Test_bucketZones(t *testing.T) {
+	tests := []struct {
+		name   string
+		zone   string
+		bucket time.Duration
+		data   string
+		expect []sample
+	}{
+		{name: "hours in Kolkata", zone: "Asia/Kolkata", bucket: time.Hour,
+			data:   "2021-01-02T10:05:00+05:30 1\n2021-01-02T10:40:00+05:30 3\n2021-01-02T11:10:00+05:30 5\n",
+			expect: []sample{{date: "2021-01-02T10:00:00+05:30", datum: 4}, {date: "2021-01-02T11:00:00+05:30", datum: 5}}},
+		{name: "days in Toronto", zone: "America/Toronto", bucket: 24 * time.Hour,
+			data:   "2021-01-02T08:00:00-05:00 1\n2021-01-02T20:00:00-05:00 3\n2021-01-03T01:00:00-05:00 5\n",
+			expect: []sample{{date: "2021-01-02T00:00:00-05:00", datum: 4}, {date: "2021-01-03T00:00:00-05:00", datum: 5}}},
+		{name: "no dates in Toronto", zone: "America/Toronto", bucket: 10 * time.Minute,
+			data:   "10:20 1\n10:25 3\n10:32 5\n",
+			expect: []sample{{date: "10:20", datum: 4}, {date: "10:30", datum: 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Skipf("no time zone database: %v", err)
+			}
+			in, err := newInput(strings.NewReader(tt.data), Options{Bucket: tt.bucket, Aggregate: "sum", Location: loc})
+			if err != nil {
+				t.Fatalf("newInput() returned %v", err)
+			}
+			for _, expect := range tt.expect {
+				s, err := in.next()
+				if err != nil || s.date != expect.date || s.datum != expect.datum {
+					t.Errorf("next() = %q %g, %v, expected %q %g", s.date, s.datum, err, expect.date, expect.datum)
+				}
+			}
+		})
+	}
+}
+
+// Test_bucketAttribute buckets counts and their sample sizes together,
+// so the proportion of each bucket is the proportion of its lines.
+func Test_bucketAttribute(t *testing.T) {
+	var sb strings.Builder
+
+	for m := 0; m < 40; m += 5 {
+		failed := 5
+		if m >= 30 {
+			failed = 12
+		}
+		fmt.Fprintf(&sb, "10:%02d %d 100\n", m, failed)
+	}
+	rules, _ := RuleSet("ThreeSigma")
+	for _, aggregate := range []string{"mean", "sum"} {
+		res, err := Analyze(strings.NewReader(sb.String()), Options{NSamples: 3, Attribute: "p", Rules: rules,
+			Bucket: 10 * time.Minute, Aggregate: aggregate})
+		if err != nil {
+			t.Fatalf("%s: Analyze() returned %v", aggregate, err)
+		}
+		if len(res.Events) != 1 {
+			t.Fatalf("%s: Analyze() returned %d events, expected 1", aggregate, len(res.Events))
+		}
+		if e := res.Events[0]; e.Datum != 0.12 || e.Mean != 0.05 || e.Indicator("ThreeSigma") != 3 {
+			t.Errorf("%s: 10:30 was %g against %g, %v, expected 0.12 against 0.05, ThreeSigma 3",
+				aggregate, e.Datum, e.Mean, e.Signals)
+		}
+	}
+
+	_, err := Analyze(strings.NewReader(sb.String()), Options{NSamples: 3, Attribute: "p", Bucket: 10 * time.Minute, Aggregate: "p95"})
+	if err == nil {
+		t.Errorf("Analyze() returned no error for a p95 of counts and sizes")
+	}
+}
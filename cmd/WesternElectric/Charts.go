@@ -0,0 +1,116 @@
+package WesternElectric
+
+import (
+	"fmt"
+	"math"
+)
+
+// Chart is a control chart with a statistic of its own, such as an
+// EWMA, run alongside the rules on the same points.
+type Chart interface {
+	// Name identifies the chart, in signals.
+	Name() string
+	// Columns names the values the chart reports, for table headers.
+	Columns() []string
+	// Add adds a point, returning the chart's values and an
+	// indicator, as with a rule, if it signalled, or 0 if not.
+	Add(p Point) ([]float64, int)
+}
+
+// Primed is a chart that needs to see the data from before judging
+// starts, such as the moving range, whose first range is from the
+// last datum of the warm-up or the baseline.
+type Primed interface {
+	Prime(datum float64)
+}
+
+// EWMAChart is an exponentially-weighted moving average control chart. The
+// smoothed statistic z = lambda*x + (1-lambda)*z is compared to limits that
+// widen from the first point to the asymptotic
+//
+//	centre +/- L*sigma*sqrt(lambda/(2-lambda))
+//
+// which makes it far more sensitive to small, persistent shifts than
+// ThreeSigma. The centre and sigma are those each point was judged
+// against, usually fixed phase I limits. See Montgomery, "Introduction
+// to Statistical Quality Control", section 9.2.
+type EWMAChart struct {
+	Lambda float64 // the weight of each new point, 0 < lambda <= 1
+	L      float64 // the width of the limits, in sigmas
+
+	z float64 // the statistic
+	i int     // the number of points so far
+}
+
+// NewEWMAChart creates an EWMA chart. Montgomery suggests a lambda
+// of 0.05 to 0.25, with an L of about 3, or 2.7 for a lambda of 0.1.
+func NewEWMAChart(lambda, L float64) (*EWMAChart, error) {
+	if lambda <= 0 || lambda > 1 {
+		return nil, fmt.Errorf("the EWMA chart's lambda must be > 0 and <= 1, not %g", lambda)
+	}
+	if L <= 0 {
+		return nil, fmt.Errorf("the EWMA chart's L must be > 0, not %g", L)
+	}
+	return &EWMAChart{Lambda: lambda, L: L}, nil
+}
+
+func (c *EWMAChart) Name() string { return "EWMA" }
+
+func (c *EWMAChart) Columns() []string {
+	return []string{"ewma", "ewma+limit", "ewma-limit"}
+}
+
+// Add updates the statistic, and returns it and its upper and lower
+// limits. It signals 1 above the upper limit, -1 below the lower.
+func (c *EWMAChart) Add(p Point) ([]float64, int) {
+	var rc int
+
+	if c.i == 0 {
+		// start at the centre line
+		c.z = p.Average
+	}
+	c.i++
+	c.z = c.Lambda*p.Datum + (1-c.Lambda)*c.z
+
+	// the limits widen with i, towards the asymptote
+	width := c.L * p.SD * math.Sqrt(c.Lambda/(2-c.Lambda)*(1-math.Pow(1-c.Lambda, float64(2*c.i))))
+	upper, lower := p.Average+width, p.Average-width
+	switch {
+	case c.z > upper:
+		rc = 1
+	case c.z < lower:
+		rc = -1
+	}
+	return []float64{c.z, upper, lower}, rc
+}
+
+// newCharts creates the charts the options ask for, in order.
+func newCharts(opts Options) ([]Chart, error) {
+	var charts []Chart
+
+	if opts.Lambda != 0 {
+		c, err := NewEWMAChart(opts.Lambda, opts.L)
+		if err != nil {
+			return nil, err
+		}
+		charts = append(charts, c)
+	}
+	if opts.IMR {
+		charts = append(charts, NewMRChart())
+	}
+	if opts.Subgroup != 0 {
+		c, err := NewSpreadChart(opts.Subgroup, opts.Spread == "sd")
+		if err != nil {
+			return nil, err
+		}
+		charts = append(charts, c)
+	}
+	if opts.CUSUM {
+		c, err := NewCUSUMChart(opts.K, opts.H)
+		if err != nil {
+			return nil, err
+		}
+		charts = append(charts, c)
+	}
+	return charts, nil
+}
@@ -0,0 +1,79 @@
+package WesternElectric
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// montgomery is the data from Montgomery's "Introduction to Statistical
+// Quality Control", 6th edition, table 9.1, used for both the CUSUM and
+// EWMA examples. The first 20 points are from a process with a mean of
+// 10 and sigma of 1, the last 10 from one that has shifted up to 11.
+var montgomery = []float64{
+	9.45, 7.99, 9.29, 11.66, 12.16, 10.18, 8.04, 11.46, 9.20, 10.34,
+	9.03, 11.47, 10.51, 9.40, 10.08, 9.37, 10.62, 10.31, 8.52, 10.84,
+	10.90, 9.33, 12.29, 11.50, 10.60, 11.08, 10.38, 11.62, 11.31, 10.52,
+}
+
+// montgomeryInput formats the data as lines of input
+func montgomeryInput() string {
+	var b strings.Builder
+
+	for i, x := range montgomery {
+		fmt.Fprintf(&b, "%d %g\n", i+1, x)
+	}
+	return b.String()
+}
+
+// Test_ewmaChart reproduces Montgomery's example 9.2, with lambda = 0.1,
+// L = 2.7, a centre of 10 and sigma 1, which signals first at sample 29.
+func Test_ewmaChart(t *testing.T) {
+	limits := Limits{Centre: 10, Sigma: 1, N: 20}
+	rules, _ := RuleSet("ThreeSigma")
+
+	res, err := Analyze(strings.NewReader(montgomeryInput()),
+		Options{Limits: &limits, Rules: rules, Lambda: 0.1, L: 2.7})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	// the statistic, and the limits widening from the start
+	first := res.Events[0].Charts
+	if math.Abs(first[0]-9.945) > 1e-9 || math.Abs(first[1]-10.27) > 1e-4 || math.Abs(first[2]-9.73) > 1e-4 {
+		t.Errorf("first EWMA = %v, expected 9.945, 10.27, 9.73", first)
+	}
+	second := res.Events[1].Charts
+	if math.Abs(second[0]-9.7495) > 1e-9 || math.Abs(second[1]-10.3632) > 1e-4 {
+		t.Errorf("second EWMA = %v, expected 9.7495, 10.3632", second)
+	}
+	// to the asymptote, 10 + 2.7*sqrt(0.1/1.9)
+	last := res.Events[len(res.Events)-1].Charts
+	if math.Abs(last[1]-(10+2.7*math.Sqrt(0.1/1.9))) > 1e-3 {
+		t.Errorf("last EWMA upper limit = %g, expected about 10.6194", last[1])
+	}
+
+	for i, e := range res.Events {
+		if e.Indicator("EWMA") != 0 {
+			if i+1 != 29 {
+				t.Errorf("EWMA signalled first at sample %d, expected 29", i+1)
+			}
+			if math.Abs(e.Charts[0]-10.6468) > 1e-4 {
+				t.Errorf("EWMA at sample 29 = %g, expected 10.6468", e.Charts[0])
+			}
+			break
+		}
+	}
+	// ThreeSigma never notices the shift
+	if res.Last != 1 {
+		t.Errorf("Analyze() last = %d, expected the EWMA's 1", res.Last)
+	}
+}
+
+func Test_ewmaChartParameters(t *testing.T) {
+	for _, p := range [][2]float64{{0, 3}, {1.5, 3}, {0.1, 0}} {
+		if _, err := NewEWMAChart(p[0], p[1]); err == nil {
+			t.Errorf("NewEWMAChart(%g, %g) returned no error", p[0], p[1])
+		}
+	}
+}
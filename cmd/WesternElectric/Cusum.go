@@ -0,0 +1,61 @@
+package WesternElectric
+
+import (
+	"fmt"
+	"math"
+)
+
+// CUSUMChart is a tabular cumulative-sum control chart. It accumulates
+// the deviations from the centre line that are more than K sigma, upwards
+// in C+ and downwards in C-, and signals when either passes H sigma.
+// That catches small, sustained shifts, like revenue being 10% down,
+// several samples before the sigma-band rules do. See Montgomery,
+// "Introduction to Statistical Quality Control", section 9.1.
+type CUSUMChart struct {
+	K float64 // the reference value, or allowance, in sigmas
+	H float64 // the decision interval, in sigmas
+
+	upper float64 // C+
+	lower float64 // C-
+}
+
+// NewCUSUMChart creates a CUSUM chart. A K of 0.5 and H of 4 or 5
+// is conventional, and detects a shift of 1 sigma well.
+func NewCUSUMChart(K, H float64) (*CUSUMChart, error) {
+	if K < 0 {
+		return nil, fmt.Errorf("the CUSUM's K must be >= 0, not %g", K)
+	}
+	if H <= 0 {
+		return nil, fmt.Errorf("the CUSUM's H must be > 0, not %g", H)
+	}
+	return &CUSUMChart{K: K, H: H}, nil
+}
+
+func (c *CUSUMChart) Name() string { return "CUSUM" }
+
+func (c *CUSUMChart) Columns() []string {
+	return []string{"C+", "C-"}
+}
+
+// Add updates the sums, and returns them. It signals 1 if C+ passed H,
+// -1 if C- did, and then starts both again from zero.
+func (c *CUSUMChart) Add(p Point) ([]float64, int) {
+	var rc int
+
+	k, h := c.K*p.SD, c.H*p.SD
+	c.upper = math.Max(0, p.Datum-(p.Average+k)+c.upper)
+	c.lower = math.Max(0, (p.Average-k)-p.Datum+c.lower)
+	values := []float64{c.upper, c.lower}
+
+	switch {
+	case c.upper > h:
+		rc = 1
+	case c.lower > h:
+		rc = -1
+	}
+	if rc != 0 {
+		// reset after a signal
+		c.upper, c.lower = 0, 0
+	}
+	return values, rc
+}
@@ -0,0 +1,68 @@
+package WesternElectric
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// Test_cusum reproduces Montgomery's example 9.1, with K = 0.5 and
+// H = 5, a centre of 10 and sigma 1, which signals at sample 29 with
+// C+ = 5.28, then resets.
+func Test_cusum(t *testing.T) {
+	limits := Limits{Centre: 10, Sigma: 1, N: 20}
+	rules, _ := RuleSet("ThreeSigma")
+
+	res, err := Analyze(strings.NewReader(montgomeryInput()),
+		Options{Limits: &limits, Rules: rules, CUSUM: true, K: 0.5, H: 5})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	tests := []struct {
+		sample int
+		upper  float64
+		lower  float64
+		signal int
+	}{
+		{sample: 1, upper: 0, lower: 0.05},
+		{sample: 3, upper: 0, lower: 1.77},
+		{sample: 5, upper: 2.82, lower: 0},
+		{sample: 28, upper: 4.47, lower: 0},
+		{sample: 29, upper: 5.28, lower: 0, signal: 1},
+		{sample: 30, upper: 0.02, lower: 0}, // after the reset
+	}
+	for _, tt := range tests {
+		e := res.Events[tt.sample-1]
+		if math.Abs(e.Charts[0]-tt.upper) > 0.005 || math.Abs(e.Charts[1]-tt.lower) > 0.005 {
+			t.Errorf("sample %d: C+, C- = %0.2f, %0.2f, expected %0.2f, %0.2f",
+				tt.sample, e.Charts[0], e.Charts[1], tt.upper, tt.lower)
+		}
+		if rc := e.Indicator("CUSUM"); rc != tt.signal {
+			t.Errorf("sample %d: CUSUM = %d, expected %d", tt.sample, rc, tt.signal)
+		}
+	}
+	for i, e := range res.Events[:28] {
+		if e.Indicator("CUSUM") != 0 {
+			t.Errorf("CUSUM signalled at sample %d, expected 29", i+1)
+		}
+	}
+}
+
+// Test_cusumBelow checks a downward shift signals -1.
+func Test_cusumBelow(t *testing.T) {
+	var rc int
+
+	c, err := NewCUSUMChart(0.5, 4)
+	if err != nil {
+		t.Fatalf("NewCUSUMChart() returned %v", err)
+	}
+	for i := 0; i < 5 && rc == 0; i++ {
+		_, rc = c.Add(Point{Datum: 8.5, Average: 10, SD: 1})
+	}
+	if rc != -1 {
+		t.Errorf("CUSUM = %d, expected -1", rc)
+	}
+	if _, err := NewCUSUMChart(0.5, 0); err == nil {
+		t.Errorf("NewCUSUMChart() with no H returned no error")
+	}
+}
@@ -0,0 +1,374 @@
+package WesternElectric
+
+import (
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"sync"
+	"time"
+)
+
+// Detector applies the rules to a single series of data. It owns
+// its moving average and the history the rules use, so each series
+// gets its own, and two series analysed in the same process can't
+// corrupt each other. A detector is locked while a datum is added,
+// so it may be handed between goroutines.
+type Detector struct {
+	mu         sync.Mutex
+	add        func(s float64) (float64, float64)
+	seasonal   *movingAverage.Seasonal        // instead of add, if comparing to the same time yesterday
+	xbar       *movingAverage.XBar            // instead of add, if the data are subgroups
+	attribute  *movingAverage.AttributeWindow // instead of add, if the data are counts
+	minSamples int                            // samples in the average before judging
+	seen       int
+	residuals  bool // judge datum - average against zero
+	average    float64
+	sd         float64
+
+	rules   []Rule  // applied in order
+	history []Point // newest first, as long as the longest window
+	judged  int     // number of points in the history
+	charts  []Chart // run alongside the rules
+}
+
+// Verdict is what the rules said about one datum.
+type Verdict struct {
+	Average float64   // the average the datum was compared against
+	SD      float64   // and its standard deviation
+	Judged  bool      // false while we're still filling the moving average
+	Signals []Signal  // the rules that fired, in the order they were applied
+	Charts  []float64 // the values from the charts, in order
+}
+
+// NewDetector creates a detector that compares each datum to the
+// average and sd returned by an "add" function, such as the one
+// from movingAverage.New, and starts judging once minSamples of
+// data are in the average. If no rules are given, it uses DefaultRules.
+// A rule with a window of less than one, which Register would refuse,
+// is given the latest point, as if its window were one.
+func NewDetector(add func(s float64) (float64, float64), minSamples int, rules ...Rule) *Detector {
+	var longest int
+
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	for _, r := range rules {
+		if window(r) > longest {
+			longest = window(r)
+		}
+	}
+	return &Detector{
+		add:        add,
+		minSamples: minSamples,
+		rules:      rules,
+		history:    make([]Point, longest),
+	}
+}
+
+// NewLimitsDetector creates a detector that compares every datum to fixed
+// control limits, starting with the first.
+func NewLimitsDetector(l Limits, rules ...Rule) *Detector {
+	d := NewDetector(movingAverage.Fixed(l.Centre, l.Sigma), 0, rules...)
+	d.average, d.sd = l.Centre, l.Sigma
+	return d
+}
+
+// NewSeasonalDetector creates a detector that compares each datum to the
+// others at the same time of day or week, using AddAt, and starts judging
+// a time once minSamples of data are in its slot's average.
+func NewSeasonalDetector(s *movingAverage.Seasonal, minSamples int, rules ...Rule) *Detector {
+	d := NewDetector(nil, minSamples, rules...)
+	d.seasonal = s
+	return d
+}
+
+// NewSubgroupDetector creates a detector that compares the means of
+// subgroups, using AddSubgroup, to the X-bar chart's centre line and
+// limits, starting once minSamples subgroups are in the average.
+func NewSubgroupDetector(x *movingAverage.XBar, minSamples int, rules ...Rule) *Detector {
+	d := NewDetector(nil, minSamples, rules...)
+	d.xbar = x
+	return d
+}
+
+// NewAttributeDetector creates a detector that compares counts, using
+// AddCount, to an attribute chart's centre line and limits for their
+// sample sizes, starting once minSamples counts are in the window.
+func NewAttributeDetector(w *movingAverage.AttributeWindow, minSamples int, rules ...Rule) *Detector {
+	d := NewDetector(nil, minSamples, rules...)
+	d.attribute = w
+	return d
+}
+
+// WithCharts adds control charts to run alongside the rules.
+func (d *Detector) WithCharts(charts ...Chart) *Detector {
+	d.charts = append(d.charts, charts...)
+	return d
+}
+
+// WithResiduals makes the detector apply the rules to the residuals,
+// the differences between the data and their averages or forecasts,
+// against an average of zero.
+func (d *Detector) WithResiduals() *Detector {
+	d.residuals = true
+	return d
+}
+
+// Add applies the rules to a datum, then adds it to the moving average.
+func (d *Detector) Add(datum float64) Verdict {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	v := Verdict{Average: d.average, SD: d.sd}
+	if d.seen >= d.minSamples {
+		// see if we break any of the rules, but only once we have an average to use
+		v = d.judgeAgainst(Point{Datum: datum, Average: d.average, SD: d.sd})
+	} else {
+		d.prime(datum, d.average)
+	}
+	d.average, d.sd = d.add(datum)
+	d.seen++
+	return v
+}
+
+// AddAt applies the rules to a datum taken at a time, then adds it to
+// the seasonal average. Without one, it's just Add.
+func (d *Detector) AddAt(t time.Time, datum float64) Verdict {
+	if d.seasonal == nil {
+		return d.Add(datum)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	average, sd, seen := d.seasonal.Stats(t)
+	v := Verdict{Average: average, SD: sd}
+	if seen >= d.minSamples {
+		// as above, but with the average for this time of day
+		v = d.judgeAgainst(Point{Datum: datum, Average: average, SD: sd})
+	} else {
+		d.prime(datum, average)
+	}
+	d.seasonal.Add(t, datum)
+	return v
+}
+
+// Judge applies the rules to a point that has already been compared
+// to an average, without touching the moving average.
+func (d *Detector) Judge(p Point) Verdict {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.judge(p)
+}
+
+// AddSubgroup applies the rules to the mean of a subgroup, then adds
+// the subgroup to the X-bar average, or just its mean to the moving
+// average if there isn't one.
+func (d *Detector) AddSubgroup(g movingAverage.Subgroup) Verdict {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	v := Verdict{Average: d.average, SD: d.sd}
+	if d.seen >= d.minSamples {
+		v = d.judgeAgainst(Point{Datum: g.Mean, Average: d.average, SD: d.sd, Group: &g})
+	} else {
+		d.prime(g.Mean, d.average)
+	}
+	if d.xbar != nil {
+		d.average, d.sd = d.xbar.Add(g)
+	} else {
+		d.average, d.sd = d.add(g.Mean)
+	}
+	d.seen++
+	return v
+}
+
+// AddCount applies the rules to a count in a sample of size, as the
+// attribute chart plots it, then adds it to the chart's window. Without
+// an attribute chart, it's just Add.
+func (d *Detector) AddCount(count, size float64) Verdict {
+	if d.attribute == nil {
+		return d.Add(count)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	centre, sigma := d.attribute.Limits(size)
+	v := Verdict{Average: centre, SD: sigma}
+	if d.seen >= d.minSamples {
+		v = d.judgeAgainst(Point{Datum: d.attribute.Value(count, size), Average: centre, SD: sigma})
+	} else {
+		d.prime(d.attribute.Value(count, size), centre)
+	}
+	d.attribute.Add(count, size)
+	d.seen++
+	return v
+}
+
+// Reset forgets the history the rules look back over, so they start
+// afresh, as they do at the beginning, but keeps the moving average.
+func (d *Detector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := range d.history {
+		d.history[i] = Point{}
+	}
+	d.judged = 0
+}
+
+// Fill adds a datum standing in for a missing one, taken at a time, to
+// the moving average, and to the history, so the rules' windows keep in
+// step with the clock, but doesn't judge it.
+func (d *Detector) Fill(t time.Time, datum float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.fill(t, datum)
+}
+
+// Carry fills a missing datum with the average, carrying it forward.
+func (d *Detector) Carry(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.seasonal != nil {
+		average, _, seen := d.seasonal.Stats(t)
+		if seen > 0 {
+			// there's nothing to carry into an empty slot
+			d.fill(t, average)
+		}
+		return
+	}
+	d.fill(t, d.average)
+}
+
+// fill is Fill, for a detector with a moving or seasonal average. The
+// caller holds the lock.
+func (d *Detector) fill(t time.Time, datum float64) {
+	p := Point{Datum: datum, Average: d.average, SD: d.sd}
+	seen := d.seen
+	if d.seasonal != nil {
+		p.Average, p.SD, seen = d.seasonal.Stats(t)
+		d.seasonal.Add(t, datum)
+	} else {
+		d.average, d.sd = d.add(datum)
+		d.seen++
+	}
+	if seen < d.minSamples {
+		// it wouldn't have been judged, so isn't in the history
+		d.prime(datum, p.Average)
+		return
+	}
+	if d.residuals {
+		p.Datum, p.Average = p.Datum-p.Average, 0
+	}
+	d.record(p)
+}
+
+// Prime shows the charts that need it a datum that won't be judged,
+// such as the last of a phase I baseline.
+func (d *Detector) Prime(datum float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.prime(datum, d.average)
+}
+
+// prime shows the charts that need it a datum that isn't being judged,
+// or its residual from average. The caller holds the lock.
+func (d *Detector) prime(datum, average float64) {
+	if d.residuals {
+		datum -= average
+	}
+	for _, c := range d.charts {
+		if p, ok := c.(Primed); ok {
+			p.Prime(datum)
+		}
+	}
+}
+
+// judgeAgainst judges a point, or its residual against zero,
+// reporting its average either way. The caller holds the lock.
+func (d *Detector) judgeAgainst(p Point) Verdict {
+	if !d.residuals {
+		return d.judge(p)
+	}
+	average := p.Average
+	p.Datum, p.Average = p.Datum-average, 0
+	v := d.judge(p)
+	v.Average = average
+	return v
+}
+
+// judge records the point in the history and applies the rules. The
+// caller holds the lock.
+func (d *Detector) judge(p Point) Verdict {
+	v := Verdict{Average: p.Average, SD: p.SD, Judged: true}
+
+	d.record(p)
+	for _, r := range d.rules {
+		n := window(r)
+		if n > d.judged {
+			n = d.judged
+		}
+		if rc := r.Evaluate(d.history[:n]); rc != 0 {
+			v.Signals = append(v.Signals, Signal{Rule: r.Name(), Indicator: rc, Severity: severity(r)})
+		}
+	}
+	for _, c := range d.charts {
+		values, rc := c.Add(p)
+		v.Charts = append(v.Charts, values...)
+		if rc != 0 {
+			v.Signals = append(v.Signals, Signal{Rule: c.Name(), Indicator: rc})
+		}
+	}
+	return v
+}
+
+// record adds a point to the history. The caller holds the lock.
+func (d *Detector) record(p Point) {
+	d.history = shiftPoints(d.history)
+	d.history[0] = p
+	if d.judged < len(d.history) {
+		d.judged++
+	}
+}
+
+// window returns a rule's window, but at least one, as Evaluate expects.
+func window(r Rule) int {
+	if r.Window() < 1 {
+		return 1
+	}
+	return r.Window()
+}
+
+// Rules returns the rules the detector applies, in order.
+func (d *Detector) Rules() []Rule {
+	return d.rules
+}
+
+// Last returns the last of the rules that fired, in the order they
+// are applied, or 0 if none did. Warnings don't count.
+func (v Verdict) Last() int {
+	return last(v.Signals)
+}
+
+// last returns the indicator of the last rejection in a list of signals
+func last(signals []Signal) int {
+	var rc int
+
+	for _, s := range signals {
+		if s.Severity == SeverityReject {
+			rc = s.Indicator
+		}
+	}
+	return rc
+}
+
+// shiftPoints moves everything to the right, like shiftRight
+func shiftPoints(window []Point) []Point {
+	for i := len(window) - 1; i > 0; i-- {
+		window[i] = window[i-1]
+	}
+	window[0] = Point{}
+	return window
+}
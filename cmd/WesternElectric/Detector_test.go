@@ -0,0 +1,84 @@
+package WesternElectric
+
+import (
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"sync"
+	"testing"
+)
+
+// Test_detectorsAreIndependent feeds two series to two detectors, and
+// checks that a step in one doesn't leak into the other's windows.
+func Test_detectorsAreIndependent(t *testing.T) {
+	step := []float64{1, 2, 1, 2, 1, 2, 1, 2, 40, 41}
+	flat := []float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+
+	stepper := NewDetector(movingAverage.New(5), 5)
+	steady := NewDetector(movingAverage.New(5), 5)
+	var got, quiet int
+	for i := range step {
+		if rc := stepper.Add(step[i]).Last(); rc != 0 {
+			got = rc
+		}
+		if rc := steady.Add(flat[i]).Last(); rc != 0 {
+			quiet = rc
+		}
+	}
+	if got == 0 {
+		t.Errorf("stepped detector didn't report the step")
+	}
+	if quiet != 0 {
+		t.Errorf("steady detector reported %d, expected 0", quiet)
+	}
+}
+
+// Test_detectorsInGoroutines runs the same series through many
+// detectors at once, which should all agree. Run with -race.
+func Test_detectorsInGoroutines(t *testing.T) {
+	data := []float64{1, 2, 3, 4, 5, 4, 3, 0, 99}
+	results := make([]int, 20)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var v Verdict
+			d := NewDetector(movingAverage.New(5), 5)
+			for _, datum := range data {
+				v = d.Add(datum)
+			}
+			results[i] = v.Last()
+		}(i)
+	}
+	wg.Wait()
+	for i, rc := range results {
+		if rc != 3 {
+			t.Errorf("detector %d: ThreeSigma = %d, expected 3", i, rc)
+		}
+	}
+}
+
+// noWindow is a rule that Register would refuse, with no window, which
+// fires on a point over 3.
+type noWindow struct{}
+
+func (noWindow) Name() string { return "noWindow" }
+func (noWindow) Window() int  { return 0 }
+func (noWindow) Evaluate(history []Point) int {
+	if history[0].Datum > 3 {
+		return 5
+	}
+	return 0
+}
+
+// Test_zeroWindow checks a rule with no window still sees the latest point.
+func Test_zeroWindow(t *testing.T) {
+	d := NewDetector(movingAverage.Fixed(0, 1), 0, noWindow{})
+
+	if v := d.Add(1); v.Last() != 0 {
+		t.Errorf("Add(1) = %d, expected 0", v.Last())
+	}
+	if v := d.Add(4); v.Last() != 5 {
+		t.Errorf("Add(4) = %d, expected 5", v.Last())
+	}
+}
@@ -0,0 +1,397 @@
+package WesternElectric
+
+import (
+	"context"
+	"fmt"
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event is a datum that was judged, and what the rules said about it.
+type Event struct {
+	Key     string    // the series, if the input has a key column
+	Date    string    // the datestamp or other initial field, as read
+	Time    time.Time // the datestamp, if it's a time we recognize
+	Datum   float64   // the value
+	Mean    float64   // the moving average it was compared against
+	SD      float64   // and the standard deviation
+	Signals []Signal  // the rules that fired, if any
+	Charts  []float64 // the values from the control charts, if any
+	Missing int       // if it's a gap, the number of data missing after Date
+}
+
+// Signal is a rule that fired, and which way.
+type Signal struct {
+	Rule      string   // the rule's name, such as "TwoSigma"
+	Indicator int      // what the rule returned, such as 3 or -2
+	Severity  Severity // whether it's a rejection or just a warning
+}
+
+// Direction is +1 for a signal above the average, -1 for one below.
+func (s Signal) Direction() int {
+	if s.Indicator < 0 {
+		return -1
+	}
+	return 1
+}
+
+// Anomalous reports true if any rule fired, even just with a warning.
+func (e Event) Anomalous() bool {
+	return len(e.Signals) > 0
+}
+
+// Rejected reports true if any rule fired that wasn't just a warning.
+func (e Event) Rejected() bool {
+	return last(e.Signals) != 0
+}
+
+// Indicator returns what the named rule returned, or 0 if it didn't fire.
+func (e Event) Indicator(rule string) int {
+	for _, s := range e.Signals {
+		if s.Rule == rule {
+			return s.Indicator
+		}
+	}
+	return 0
+}
+
+// When returns the datestamp in a time layout, or as it was read if
+// there's no layout, or it wasn't a time we recognized.
+func (e Event) When(layout string) string {
+	if layout == "" || e.Time.IsZero() {
+		return e.Date
+	}
+	return e.Time.Format(layout)
+}
+
+// Last returns the indicator of the last rule that fired, or 0.
+// Warnings don't count.
+func (e Event) Last() int {
+	return last(e.Signals)
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	Events   []Event  // the events found, from Analyze
+	Last     int      // the indicator of the last anomaly, or 0
+	Read     int      // the number of lines read
+	Skipped  int      // the number of malformed lines skipped
+	Limits   *Limits  // the fixed limits used, if any, or the last computed, if there are several series
+	Gaps     int      // the number of gaps in the data, if there's an Interval
+	Missing  int      // and the number of data missing from them
+	Unjudged []string // the keys of any series too short for phase I, which weren't judged
+	Err      error    // from Stream, the error that stopped it, if any
+}
+
+// LineError is a malformed line, and what was wrong with it.
+type LineError struct {
+	Line   int      // line number in the input, from 1
+	Record []string // the fields we read, if any
+	Err    error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d, %q: %v", e.Line, strings.Join(e.Record, " "), e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Analyze reads lines containing a datestamp and a value, applies the rules
+// to them, comparing each to a moving average of opts.NSamples, and returns
+// an event for every datum judged. The first MinSamples data, by default
+// NSamples, are used to fill the moving average, and produce no events.
+func Analyze(r io.Reader, opts Options) (Result, error) {
+	var events []Event
+
+	res, err := scan(r, opts, func(e Event) {
+		events = append(events, e)
+	})
+	res.Events = events
+	return res, err
+}
+
+// Stream is Analyze for long-running inputs: it sends each event on the
+// returned channel as it is found, and closes it at the end of the input.
+// The totals, and the error if any, are then sent on the second channel.
+// To stop early, cancel ctx: the events are then dropped, the input is
+// read no further, and the channels are closed, with ctx's error as the
+// result's. A Read that is blocked, as on a pipe, isn't interrupted, so
+// close the reader too if it might be.
+func Stream(ctx context.Context, r io.Reader, opts Options) (<-chan Event, <-chan Result) {
+	events := make(chan Event)
+	done := make(chan Result, 1)
+
+	go func() {
+		defer close(done)
+		res, err := scan(contextReader{ctx: ctx, r: r}, opts, func(e Event) {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+			}
+		})
+		close(events)
+		res.Err = err
+		done <- res
+	}()
+	return events, done
+}
+
+// contextReader is a reader that stops once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// scan reads the input and applies the rules, passing each event
+// to emit as it goes. If the input has a key column, each series in
+// it gets its own detector, the first time it's seen.
+func scan(fp io.Reader, opts Options, emit func(Event)) (res Result, err error) {
+	var keys []string // in the order they were first seen
+
+	// the first series also checks the options, before we read anything
+	first, err := newSeries(opts)
+	if err != nil {
+		return res, err
+	}
+	if opts.Limits != nil {
+		res.Limits = opts.Limits
+	}
+	all := make(map[string]*series)
+
+	in, err := newInput(fp, opts)
+	if err != nil {
+		return res, err
+	}
+	defer func() {
+		res.Read, res.Skipped = in.read, in.skipped
+	}()
+	for {
+		s, err := in.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, err
+		}
+		sr := all[s.key]
+		if sr == nil {
+			if sr, first = first, nil; sr == nil {
+				if sr, err = newSeries(opts); err != nil {
+					return res, err
+				}
+			}
+			sr.limits.Key = s.key
+			all[s.key] = sr
+			keys = append(keys, s.key)
+		}
+		sr.add(s, opts, &res, emit)
+	}
+	for _, key := range keys {
+		if sr := all[key]; sr.detector == nil {
+			if opts.KeyColumn == "" {
+				return res, fmt.Errorf("only %d points, phase I needs %d", sr.limits.N, opts.PhaseI)
+			}
+			// a new or quiet series shouldn't spoil the rest
+			res.Unjudged = append(res.Unjudged, key)
+		}
+	}
+	if len(keys) == 0 && first.detector == nil {
+		return res, fmt.Errorf("only 0 points, phase I needs %d", opts.PhaseI)
+	}
+	return res, nil
+}
+
+// series is the state of one series of data: its detector, or the
+// baseline it's collecting in phase I, and its subgroups and gaps.
+type series struct {
+	detector  *Detector
+	attribute *movingAverage.AttributeWindow
+	baseline  func(s float64) (float64, float64)
+	subgroups *movingAverage.XBar // the baseline, for subgroups
+	limits    Limits
+	groups    *grouper
+	previous  sample // the last one, to find gaps after
+}
+
+// newSeries creates the detector the options ask for, with its own
+// moving average, charts and rule windows, or the baseline to compute
+// its limits from in phase I.
+func newSeries(opts Options) (*series, error) {
+	sr := &series{groups: newGrouper(opts)}
+
+	charts, err := newCharts(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Attribute != "" && (opts.Limits != nil || opts.PhaseI > 0) {
+		return nil, fmt.Errorf("attribute charts compute their own limits, from the last %d points", opts.NSamples)
+	}
+	if opts.Residuals && (opts.Limits != nil || opts.PhaseI > 0) {
+		// the residuals from a fixed centre line are judged just as the data are
+		return nil, fmt.Errorf("residuals need a moving average or forecast, not fixed limits")
+	}
+	switch {
+	case opts.Interval < 0:
+		return nil, fmt.Errorf("interval must be > 0, not %s", opts.Interval)
+	case opts.Interval != 0 && (opts.Gaps == GapCarry || opts.Gaps == GapInterpolate) &&
+		(opts.Subgroup != 0 || opts.Attribute != ""):
+		return nil, fmt.Errorf("gaps in subgroups or counts can't be filled, only reset or reported")
+	}
+	if opts.Subgroup != 0 {
+		// checks the options, as well as making the phase I baseline
+		if sr.subgroups, err = newXBar(opts, true); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case opts.Limits != nil:
+		// phase II only, with limits we were given
+		sr.detector = NewLimitsDetector(*opts.Limits, opts.Rules...).WithCharts(charts...)
+	case opts.PhaseI > 0:
+		// phase I, computing limits from the first points
+		if opts.PhaseI < 2 {
+			return nil, fmt.Errorf("phase I needs more than one point, not %d", opts.PhaseI)
+		}
+		sr.baseline = newBaseline(opts)
+	default:
+		// a detector, with its own moving average
+		if opts.NSamples < 2 {
+			return nil, fmt.Errorf("number of samples must be > 1, not %d", opts.NSamples)
+		}
+		minSamples := opts.MinSamples
+		switch {
+		case minSamples != 0:
+		case opts.Average == "holtwinters":
+			// a season to start the forecasts, then some errors for the sd
+			minSamples = opts.Period + opts.NSamples
+		default:
+			minSamples = opts.NSamples
+		}
+		if minSamples < 2 {
+			return nil, fmt.Errorf("minimum number of samples must be > 1, not %d", minSamples)
+		}
+		switch {
+		case opts.Attribute != "":
+			if opts.Subgroup != 0 || opts.Season != "" || opts.IMR {
+				return nil, fmt.Errorf("attribute charts can't be used with subgroups, a season or an I-MR chart")
+			}
+			if sr.attribute, err = movingAverage.NewAttributeWindow(opts.Attribute, opts.NSamples); err != nil {
+				return nil, err
+			}
+			sr.detector = NewAttributeDetector(sr.attribute, minSamples, opts.Rules...).WithCharts(charts...)
+		case opts.Subgroup != 0:
+			x, err := newXBar(opts, false)
+			if err != nil {
+				return nil, err
+			}
+			sr.detector = NewSubgroupDetector(x, minSamples, opts.Rules...).WithCharts(charts...)
+		case opts.Season != "":
+			seasonal, err := newSeasonal(opts)
+			if err != nil {
+				return nil, err
+			}
+			sr.detector = NewSeasonalDetector(seasonal, minSamples, opts.Rules...).WithCharts(charts...)
+		default:
+			add, err := newAverage(opts)
+			if err != nil {
+				return nil, err
+			}
+			sr.detector = NewDetector(add, minSamples, opts.Rules...).WithCharts(charts...)
+		}
+		if opts.Residuals {
+			sr.detector.WithResiduals()
+		}
+	}
+	return sr, nil
+}
+
+// add applies the rules to a sample, or adds it to the phase I baseline,
+// passing any event to emit, and recording gaps and anomalies in res.
+func (sr *series) add(s sample, opts Options, res *Result, emit func(Event)) {
+	date, at, datum := s.date, s.at, s.datum
+
+	if opts.Interval != 0 && !sr.previous.at.IsZero() {
+		if n := missing(sr.previous.at, at, opts.Interval); n > 0 {
+			res.Gaps++
+			res.Missing += n
+			if sr.detector != nil {
+				// phase I just counts them
+				fill(sr.detector, opts.Gaps, sr.previous, s, n, opts.Interval, emit)
+			}
+		}
+	}
+	sr.previous = s
+
+	var group *movingAverage.Subgroup
+	if sr.groups != nil {
+		// judge the subgroups, once they're full
+		first, g, full := sr.groups.add(s)
+		if !full {
+			return
+		}
+		date, at, datum, group = first.date, first.at, g.Mean, &g
+	}
+
+	if sr.detector == nil {
+		// still in phase I, collecting the baseline
+		limits := &sr.limits
+		if group != nil {
+			limits.Centre, limits.Sigma = sr.subgroups.Add(*group)
+		} else {
+			limits.Centre, limits.Sigma = sr.baseline(datum)
+		}
+		limits.N++
+		if limits.N == 1 {
+			limits.From = date
+		}
+		limits.To = date
+		if limits.N == opts.PhaseI {
+			res.Limits = limits
+			if opts.OnLimits != nil {
+				opts.OnLimits(*limits)
+			}
+			charts, _ := newCharts(opts) // checked by newSeries
+			sr.detector = NewLimitsDetector(*limits, opts.Rules...).WithCharts(charts...)
+			sr.detector.Prime(datum)
+		}
+		return
+	}
+
+	var v Verdict
+	switch {
+	case group != nil:
+		v = sr.detector.AddSubgroup(*group)
+	case sr.attribute != nil:
+		v = sr.detector.AddCount(datum, s.size)
+		datum = sr.attribute.Value(datum, s.size)
+	default:
+		v = sr.detector.AddAt(at, datum)
+	}
+	if v.Judged {
+		e := Event{
+			Key:     s.key,
+			Date:    date,
+			Time:    at,
+			Datum:   datum,
+			Mean:    v.Average,
+			SD:      v.SD,
+			Signals: v.Signals,
+			Charts:  v.Charts,
+		}
+		if rc := e.Last(); rc != 0 {
+			res.Last = rc
+		}
+		emit(e)
+	}
+}
@@ -0,0 +1,289 @@
+package WesternElectric
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+const spike = `#time value
+1 1
+2 2
+3 3
+4 4
+5 5
+6 4
+7 3
+8 0
+9 99
+`
+
+func Test_analyze(t *testing.T) {
+	res, err := Analyze(strings.NewReader(spike), Options{NSamples: 5})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	events := res.Events
+	// the first nSamples data only fill the moving average
+	if len(events) != 4 {
+		t.Fatalf("Analyze() returned %d events, expected 4", len(events))
+	}
+	last := events[len(events)-1]
+	if last.Date != "9" || last.Datum != 99 {
+		t.Errorf("last event was %q %g, expected \"9\" 99", last.Date, last.Datum)
+	}
+	if !last.Anomalous() || last.Indicator("ThreeSigma") != 3 {
+		t.Errorf("last event signals = %v, expected ThreeSigma 3", last.Signals)
+	}
+	if last.Signals[0].Direction() != 1 {
+		t.Errorf("ThreeSigma direction = %d, expected 1", last.Signals[0].Direction())
+	}
+}
+
+// Test_analyzeGzip shows any io.Reader will do.
+func Test_analyzeGzip(t *testing.T) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(spike))
+	_ = zw.Close()
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() returned %v", err)
+	}
+	res, err := Analyze(zr, Options{NSamples: 5})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	if rc := res.Last; rc != 3 {
+		t.Errorf("Analyze() last = %d, expected 3", rc)
+	}
+}
+
+func Test_stream(t *testing.T) {
+	var n, last int
+
+	events, done := Stream(context.Background(), strings.NewReader(spike), Options{NSamples: 5})
+	for e := range events {
+		n++
+		last = e.Last()
+	}
+	if res := <-done; res.Err != nil || res.Read != 9 {
+		t.Errorf("Stream() read %d lines, returned %v", res.Read, res.Err)
+	}
+	if n != 4 || last != 3 {
+		t.Errorf("Stream() sent %d events ending in %d, expected 4 ending in 3", n, last)
+	}
+}
+
+func Test_streamError(t *testing.T) {
+	events, done := Stream(context.Background(), iotest.ErrReader(errors.New("connection reset")), Options{NSamples: 5})
+	for range events {
+	}
+	if res := <-done; res.Err == nil {
+		t.Errorf("Stream() returned no error for a failing reader")
+	}
+}
+
+// Test_streamCancel stops reading an endless input after the first event.
+func Test_streamCancel(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(pw, "%d %d\n", i, i%3); err != nil {
+				return
+			}
+		}
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	events, done := Stream(ctx, pr, Options{NSamples: 5})
+
+	<-events
+	cancel() // and stop reading the rest
+	select {
+	case res := <-done:
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Errorf("Stream() returned %v, expected it was cancelled", res.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Stream() didn't stop when cancelled")
+	}
+	_ = pr.Close()
+}
+
+// Test_malformed tries each policy on a file with a bad value and a short line.
+func Test_malformed(t *testing.T) {
+	const bad = "1 1\n2 2\n3 three\n4\n5 5\n"
+	tests := []struct {
+		name    string
+		policy  Policy
+		skipped int
+		line    int // of the error, if any
+	}{
+		{name: "skip", policy: PolicySkip, skipped: 2},
+		{name: "count", policy: PolicyCount, skipped: 2},
+		{name: "abort", policy: PolicyAbort, skipped: 0, line: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Analyze(strings.NewReader(bad), Options{NSamples: 2, Malformed: tt.policy})
+			if res.Skipped != tt.skipped {
+				t.Errorf("Analyze() skipped %d lines, expected %d", res.Skipped, tt.skipped)
+			}
+			var lerr *LineError
+			switch {
+			case tt.line == 0 && err != nil:
+				t.Errorf("Analyze() returned %v, expected no error", err)
+			case tt.line != 0 && !errors.As(err, &lerr):
+				t.Errorf("Analyze() returned %v, expected a LineError", err)
+			case tt.line != 0 && (lerr.Line != tt.line || lerr.Record[1] != "three"):
+				t.Errorf("Analyze() returned %v, expected line %d, \"three\"", err, tt.line)
+			}
+		})
+	}
+}
+
+func Test_averages(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		fail bool
+	}{
+		{name: "simple", opts: Options{NSamples: 5, Average: "simple"}},
+		{name: "median", opts: Options{NSamples: 5, Average: "median"}},
+		{name: "ewma", opts: Options{NSamples: 5, Average: "ewma", Alpha: 0.3}},
+		{name: "ewma, no alpha", opts: Options{NSamples: 5, Average: "ewma"}, fail: true},
+		{name: "unknown", opts: Options{NSamples: 5, Average: "mode"}, fail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Analyze(strings.NewReader(spike), tt.opts)
+			switch {
+			case tt.fail && err == nil:
+				t.Errorf("Analyze() returned no error")
+			case !tt.fail && err != nil:
+				t.Errorf("Analyze() returned %v", err)
+			case !tt.fail && res.Last != 3:
+				t.Errorf("Analyze() last = %d, expected the spike, 3", res.Last)
+			}
+		})
+	}
+}
+
+// Test_minSamples starts judging before the moving average is full.
+func Test_minSamples(t *testing.T) {
+	tests := []struct {
+		name       string
+		minSamples int
+		events     int
+		fail       bool
+	}{
+		{name: "default", minSamples: 0, events: 4},
+		{name: "early", minSamples: 2, events: 7},
+		{name: "late", minSamples: 8, events: 1},
+		{name: "too few", minSamples: 1, fail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Analyze(strings.NewReader(spike), Options{NSamples: 5, MinSamples: tt.minSamples})
+			switch {
+			case tt.fail && err == nil:
+				t.Errorf("Analyze() returned no error")
+			case !tt.fail && err != nil:
+				t.Errorf("Analyze() returned %v", err)
+			case len(res.Events) != tt.events:
+				t.Errorf("Analyze() returned %d events, expected %d", len(res.Events), tt.events)
+			}
+		})
+	}
+}
+
+// Test_seasonal compares the night to past nights, and the day to past days,
+// so a busy night stands out although it's normal for the daytime.
+func Test_seasonal(t *testing.T) {
+	const days = "03:00 10\n15:00 100\n03:00 11\n15:00 101\n03:00 9\n15:00 99\n03:00 100\n15:00 100\nnoon 1\n"
+
+	res, err := Analyze(strings.NewReader(days), Options{NSamples: 3, Season: "day", Slot: time.Hour})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	if res.Skipped != 1 {
+		t.Errorf("Analyze() skipped %d lines, expected 1, \"noon\"", res.Skipped)
+	}
+	events := res.Events
+	if len(events) != 2 {
+		t.Fatalf("Analyze() returned %d events, expected 2", len(events))
+	}
+	if events[0].Mean != 10 || events[0].Indicator("ThreeSigma") != 3 {
+		t.Errorf("busy night was %v against %g, expected ThreeSigma 3 against 10", events[0].Signals, events[0].Mean)
+	}
+	if events[1].Mean != 100 || events[1].Anomalous() {
+		t.Errorf("busy day was %v against %g, expected nothing against 100", events[1].Signals, events[1].Mean)
+	}
+
+	for _, opts := range []Options{
+		{NSamples: 3, Season: "month", Slot: time.Hour},
+		{NSamples: 3, Season: "day", Slot: 7 * time.Minute},
+		{NSamples: 3, Season: "week", Slot: time.Hour}, // the times have no dates
+	} {
+		if _, err := Analyze(strings.NewReader(days), opts); err == nil {
+			t.Errorf("Analyze() returned no error for season %q, slot %s", opts.Season, opts.Slot)
+		}
+	}
+}
+
+// Test_residuals forecasts a noisy, growing daily cycle, so only the spike
+// at the end stands out, where a moving average sees anomalies throughout.
+func Test_residuals(t *testing.T) {
+	const period = 24
+	var sb strings.Builder
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20*period; i++ {
+		cycle := 20 * math.Sin(2*math.Pi*float64(i)/period)
+		fmt.Fprintf(&sb, "%d %g\n", i, 100+float64(i)+cycle+2*r.NormFloat64())
+	}
+	fmt.Fprintf(&sb, "spike %g\n", 1000.0)
+	rules, _ := RuleSet("WesternElectric")
+	rejected := func(opts Options) int {
+		var n int
+
+		opts.Rules = rules
+		res, err := Analyze(strings.NewReader(sb.String()), opts)
+		if err != nil {
+			t.Fatalf("Analyze() returned %v", err)
+		}
+		if last := res.Events[len(res.Events)-1]; last.Indicator("ThreeSigma") != 3 {
+			t.Errorf("%s: spike was %v, expected ThreeSigma 3", opts.Average, last.Signals)
+		}
+		// skip the first few periods, while the forecasts settle
+		for _, e := range res.Events[5*period : len(res.Events)-1] {
+			if e.Rejected() {
+				n++
+			}
+		}
+		return n
+	}
+
+	simple := rejected(Options{NSamples: 5})
+	forecast := rejected(Options{NSamples: 5, Average: "holtwinters",
+		Alpha: 0.1, Beta: 0.05, Gamma: 0.2, Period: period, Residuals: true})
+	t.Logf("rejected %d with a moving average, %d with forecasts", simple, forecast)
+	if forecast*10 > simple {
+		t.Errorf("forecasts rejected %d, expected under a tenth of the moving average's %d", forecast, simple)
+	}
+
+	_, err := Analyze(strings.NewReader(sb.String()), Options{NSamples: 5, Average: "holtwinters", Alpha: 0.3})
+	if err == nil {
+		t.Errorf("Analyze() returned no error for holtwinters without beta, gamma or a period")
+	}
+}
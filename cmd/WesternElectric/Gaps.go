@@ -0,0 +1,83 @@
+package WesternElectric
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// GapPolicy is what to do when data are missing: when the time
+// since the last datum is more than the Interval they're expected at.
+type GapPolicy int32
+
+const (
+	GapMissing     GapPolicy = 0 // send an event saying how many are missing
+	GapReset       GapPolicy = 1 // start the rules' windows afresh
+	GapCarry       GapPolicy = 2 // fill the gap with the average
+	GapInterpolate GapPolicy = 3 // fill it with a line from the datum before to the one after
+)
+
+var GapPolicyName = map[int32]string{
+	0: "missing",
+	1: "reset",
+	2: "carry",
+	3: "interpolate",
+}
+
+func (x GapPolicy) String() string {
+	return GapPolicyName[int32(x)]
+}
+
+// ParseGapPolicy turns a gap policy name, as used on the command line,
+// into a GapPolicy.
+func ParseGapPolicy(name string) (GapPolicy, error) {
+	for i, s := range GapPolicyName {
+		if s == name {
+			return GapPolicy(i), nil
+		}
+	}
+	return GapMissing, fmt.Errorf("unknown gap policy %q, expected missing, reset, carry or interpolate", name)
+}
+
+// missing returns how many data are missing between two times, if
+// they're expected at an interval, allowing half an interval of jitter.
+// Times without a date, such as "15:04", that go back more than half a
+// day have crossed midnight, so 23:50 to 00:20 is a gap of half an hour.
+// Otherwise, times that go backwards aren't gaps.
+func missing(from, to time.Time, interval time.Duration) int {
+	if dayless(from) && dayless(to) && from.Sub(to) > 12*time.Hour {
+		to = to.Add(24 * time.Hour)
+	}
+	n := int(math.Round(float64(to.Sub(from))/float64(interval))) - 1
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// dayless reports true if a time was read without a date, which leaves
+// it in year 0: on its first day, or, once a gap or bucket has crossed
+// midnight, just after.
+func dayless(t time.Time) bool {
+	return t.Year() == 0
+}
+
+// fill applies the policy to a gap of n data between two samples,
+// sending an event for it if the policy is to report it.
+func fill(d *Detector, policy GapPolicy, before, after sample, n int, interval time.Duration, emit func(Event)) {
+	switch policy {
+	case GapMissing:
+		emit(Event{Key: before.key, Date: before.date, Time: before.at, Missing: n})
+	case GapReset:
+		d.Reset()
+	case GapCarry:
+		for i := 1; i <= n; i++ {
+			d.Carry(before.at.Add(time.Duration(i) * interval))
+		}
+	case GapInterpolate:
+		step := (after.datum - before.datum) / float64(n+1)
+		for i := 1; i <= n; i++ {
+			d.Fill(before.at.Add(time.Duration(i)*interval), before.datum+float64(i)*step)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package WesternElectric
+
+import (
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test_gaps tries each policy on a series with two ten-minute samples
+// missing, comparing what the last datum is judged against.
+func Test_gaps(t *testing.T) {
+	const data = "10:00 2\n10:10 2\n10:20 8\n10:50 5\n11:04 5\n"
+	tests := []struct {
+		name    string
+		policy  GapPolicy
+		average float64 // of the last three, before 11:04
+		events  int
+	}{
+		{name: "missing", policy: GapMissing, average: 5, events: 3},
+		{name: "reset", policy: GapReset, average: 5, events: 2},
+		{name: "carry", policy: GapCarry, average: (4 + 14.0/3 + 5) / 3, events: 2},
+		{name: "interpolate", policy: GapInterpolate, average: 6, events: 2},
+	}
+	rules, _ := RuleSet("ThreeSigma")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Analyze(strings.NewReader(data), Options{NSamples: 3, Rules: rules,
+				Interval: 10 * time.Minute, Gaps: tt.policy})
+			if err != nil {
+				t.Fatalf("Analyze() returned %v", err)
+			}
+			// 11:04 is just late, not a gap
+			if res.Gaps != 1 || res.Missing != 2 {
+				t.Errorf("Analyze() found %d gaps, %d missing, expected 1, 2", res.Gaps, res.Missing)
+			}
+			if len(res.Events) != tt.events {
+				t.Fatalf("Analyze() returned %d events, expected %d", len(res.Events), tt.events)
+			}
+			if last := res.Events[len(res.Events)-1]; math.Abs(last.Mean-tt.average) > 1e-9 {
+				t.Errorf("last datum was compared to %g, expected %g", last.Mean, tt.average)
+			}
+			if tt.policy == GapMissing {
+				if e := res.Events[0]; e.Missing != 2 || e.Date != "10:20" || e.Anomalous() {
+					t.Errorf("first event was %+v, expected 2 missing after 10:20", e)
+				}
+			}
+		})
+	}
+
+	for _, opts := range []Options{
+		{NSamples: 3, Interval: -time.Minute},
+		{NSamples: 3, Interval: time.Minute, Gaps: GapCarry, Subgroup: 2},
+	} {
+		if _, err := Analyze(strings.NewReader(data), opts); err == nil {
+			t.Errorf("Analyze() returned no error for interval %s, gaps %s, subgroup %d",
+				opts.Interval, opts.Gaps, opts.Subgroup)
+		}
+	}
+}
+
+// Test_reset checks a reset detector starts its windows afresh.
+func Test_reset(t *testing.T) {
+	rules, _ := RuleSet("TwoSigma")
+	d := NewDetector(movingAverage.Fixed(0, 1), 0, rules...)
+
+	d.Add(2.5)
+	d.Reset()
+	if v := d.Add(2.5); v.Last() != 0 {
+		t.Errorf("after a reset, TwoSigma = %d, expected 0", v.Last())
+	}
+	if v := d.Add(2.5); v.Last() != 2 {
+		t.Errorf("TwoSigma = %d, expected 2", v.Last())
+	}
+}
+
+func Test_parseGapPolicy(t *testing.T) {
+	for _, name := range []string{"missing", "reset", "carry", "interpolate"} {
+		if p, err := ParseGapPolicy(name); err != nil || p.String() != name {
+			t.Errorf("ParseGapPolicy(%q) = %s, %v", name, p, err)
+		}
+	}
+	if _, err := ParseGapPolicy("ignore"); err == nil {
+		t.Errorf("ParseGapPolicy(\"ignore\") returned no error")
+	}
+}
+
+// Test_midnight counts a gap across midnight in times without a date.
+func Test_midnight(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		missing  int
+	}{
+		{name: "across midnight", from: "23:50", to: "00:20", missing: 2},
+		{name: "on time, across midnight", from: "23:50", to: "00:00", missing: 0},
+		{name: "out of order", from: "10:20", to: "10:10", missing: 0},
+		{name: "dated, going backwards", from: "2021-01-02T23:50:00Z", to: "2021-01-02T00:20:00Z", missing: 0},
+		{name: "dated, across midnight", from: "2021-01-02T23:50:00Z", to: "2021-01-03T00:20:00Z", missing: 2},
+	}
+	p := newTimeParser(Options{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, _ := p.parse(tt.from)
+			to, _ := p.parse(tt.to)
+			if n := missing(from, to, 10*time.Minute); n != tt.missing {
+				t.Errorf("missing(%s, %s) = %d, expected %d", tt.from, tt.to, n, tt.missing)
+			}
+		})
+	}
+
+	res, err := Analyze(strings.NewReader("23:30 1\n23:40 1\n23:50 1\n00:20 1\n"), Options{NSamples: 2, Interval: 10 * time.Minute})
+	if err != nil || res.Gaps != 1 || res.Missing != 2 {
+		t.Errorf("Analyze() found %d gaps, %d missing, %v, expected 1, 2", res.Gaps, res.Missing, err)
+	}
+}
@@ -0,0 +1,329 @@
+package WesternElectric
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// input reads lines containing a datestamp or other initial field, and
+// a value, applying the policy for malformed lines as it goes.
+type input struct {
+	r       records
+	header  int // lines of header read before r started
+	policy  Policy
+	date    []int // the columns of the datestamp, joined with spaces
+	value   int   // and of the value
+	sizes   int   // and of the sample size, for an attribute chart
+	key     int   // and of the series' key, or -1 if there's just one series
+	times   *timeParser
+	buckets *bucketer // if the lines are to be grouped into buckets of time
+	timed   bool      // the datestamps must be times: for a season, a layout, gaps or buckets
+	weekly  bool      // and must have dates, for a weekly season
+	sized   bool      // read sample sizes, for an attribute chart
+	counted bool      // the values are counts, which can't be negative
+	bounded bool      // or more than their sample sizes, for p and np charts
+	size    float64   // the sample size, if a line doesn't have one
+	read    int       // lines read
+	skipped int       // malformed lines skipped
+}
+
+// records reads the fields of each line, as a csv.Reader does.
+type records interface {
+	Read() ([]string, error)
+	FieldPos(field int) (line, column int)
+}
+
+// sample is a good line of input.
+type sample struct {
+	key   string    // the series it's in, if the input has several
+	date  string    // the datestamp or other initial field, as read
+	at    time.Time // the datestamp, if it's a time we recognize
+	datum float64
+	size  float64   // the sample size, if the input is sized
+	group []float64 // the data in a bucket of time, if it's to be a subgroup
+}
+
+// newInput sets up a reader to read fields out of a file, split by the
+// delimiter the options ask for, and finds the columns they ask for,
+// by number or by name from a header line starting with "#".
+func newInput(fp io.Reader, opts Options) (*input, error) {
+	var r records
+	var err error
+	in := &input{
+		policy:  opts.Malformed,
+		times:   newTimeParser(opts),
+		timed:   opts.Season != "" || opts.TimeLayout != "" || opts.Interval != 0 || opts.Bucket != 0,
+		weekly:  opts.Season == "week",
+		sized:   needsSizes(opts),
+		counted: opts.Attribute != "",
+		bounded: opts.Attribute == "p" || opts.Attribute == "np",
+		size:    opts.SampleSize,
+	}
+
+	br := bufio.NewReader(fp)
+	header, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if header != "" {
+		in.header = 1
+	}
+	switch opts.Delimiter {
+	case "", "space":
+		r = newCSV(br, ' ')
+	case "tab":
+		r = newCSV(br, '\t')
+	case "comma":
+		r = newCSV(br, ',')
+	case "whitespace":
+		r = &fieldsReader{s: bufio.NewScanner(br)}
+	default:
+		return nil, fmt.Errorf("unknown delimiter %q, expected space, tab, comma or whitespace", opts.Delimiter)
+	}
+	in.r = r
+	if in.buckets, err = newBucketer(opts, in.times.format); err != nil {
+		return nil, err
+	}
+
+	names := split(header, opts.Delimiter)
+	dates := opts.DateColumns
+	if len(dates) == 0 {
+		dates = []string{"1"}
+	}
+	for _, name := range dates {
+		i, err := column(name, names)
+		if err != nil {
+			return nil, err
+		}
+		in.date = append(in.date, i)
+	}
+	value := opts.ValueColumn
+	if value == "" {
+		value = "2"
+	}
+	if in.value, err = column(value, names); err != nil {
+		return nil, err
+	}
+	in.sizes = in.value + 1
+	if opts.SizeColumn != "" {
+		if in.sizes, err = column(opts.SizeColumn, names); err != nil {
+			return nil, err
+		}
+	}
+	in.key = -1
+	if opts.KeyColumn != "" {
+		if in.key, err = column(opts.KeyColumn, names); err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
+
+// needsSizes reports true if the options ask for an attribute chart
+// that needs sample sizes, as well as counts.
+func needsSizes(opts Options) bool {
+	return opts.Attribute == "p" || opts.Attribute == "np" || opts.Attribute == "u"
+}
+
+// newCSV sets up a csv reader to read fields out of a file
+func newCSV(fp io.Reader, comma rune) *csv.Reader {
+	r := csv.NewReader(fp)
+	r.Comma = comma
+	r.Comment = '#'
+	r.FieldsPerRecord = -1 // ignore differences
+	r.LazyQuotes = true    // allow bad quoting
+	return r
+}
+
+// readHeader reads the first line, if it's a comment, returning
+// it without the "#", or "" if it isn't.
+func readHeader(br *bufio.Reader) (string, error) {
+	first, err := br.Peek(1)
+	if err != nil || first[0] != '#' {
+		// an empty file has no header, and no data
+		return "", nil
+	}
+	line, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("error reading the header: %w", err)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "#")), nil
+}
+
+// split splits a header into column names, as the delimiter would.
+func split(header, delimiter string) []string {
+	switch delimiter {
+	case "tab":
+		return strings.Split(header, "\t")
+	case "comma":
+		return strings.Split(header, ",")
+	}
+	return strings.Fields(header)
+}
+
+// column finds a column, numbered from 1, as awk does, or named in
+// the header, and returns its index.
+func column(name string, header []string) (int, error) {
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 1 {
+			return 0, fmt.Errorf("columns are numbered from 1, not %d", n)
+		}
+		return n - 1, nil
+	}
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i, nil
+		}
+	}
+	if len(header) == 0 {
+		return 0, fmt.Errorf("no header line to find column %q in", name)
+	}
+	return 0, fmt.Errorf("no column %q in the header, %q", name, header)
+}
+
+// fieldsReader reads fields separated by runs of spaces and tabs,
+// skipping blank lines and comments.
+type fieldsReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func (r *fieldsReader) Read() ([]string, error) {
+	for r.s.Scan() {
+		r.line++
+		fields := strings.Fields(r.s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		return fields, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// FieldPos returns the line number of the last line read. We
+// don't keep the columns.
+func (r *fieldsReader) FieldPos(field int) (int, int) {
+	return r.line, 0
+}
+
+// next returns the next good line, or the next bucket of them if the
+// options ask for buckets, or io.EOF at the end of the input.
+func (in *input) next() (sample, error) {
+	if in.buckets != nil {
+		return in.buckets.next(in.line)
+	}
+	return in.line()
+}
+
+// line returns the next good line, or io.EOF at the end of the input.
+func (in *input) line() (sample, error) {
+	for {
+		record, err := in.r.Read()
+		if err == io.EOF {
+			return sample{}, err
+		}
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			// we had a csv-reading error
+			in.read++
+			if err = in.malformed(pe.Line+in.header, record, pe.Err); err != nil {
+				return sample{}, err
+			}
+			continue
+		}
+		if err != nil {
+			// we couldn't read at all
+			return sample{}, fmt.Errorf("error reading input after line %d: %w", in.read, err)
+		}
+		in.read++
+		line, _ := in.r.FieldPos(0)
+		line += in.header
+		//log.Printf("read %q\n", record)
+
+		s, err := in.parse(record)
+		if err != nil {
+			if err = in.malformed(line, record, err); err != nil {
+				return sample{}, err
+			}
+			continue
+		}
+		if in.weekly && dayless(s.at) {
+			// every time would fall on the same day of the week
+			return sample{}, fmt.Errorf("line %d: a weekly season needs datestamps with dates, not %q", line, s.date)
+		}
+		return s, nil
+	}
+}
+
+// parse turns a record into a sample: the datestamp, which is parsed
+// if it can be, and must be if the input is timed, the value, and the
+// sample size if the input is sized and the record has one. Counts
+// must be possible ones, for their chart and sample size.
+func (in *input) parse(record []string) (sample, error) {
+	var s sample
+	var err error
+
+	dates := make([]string, len(in.date))
+	for i, c := range in.date {
+		if c >= len(record) {
+			return s, errors.New("too few fields")
+		}
+		dates[i] = record[c]
+	}
+	if in.value >= len(record) || in.key >= len(record) {
+		return s, errors.New("too few fields")
+	}
+	if in.key >= 0 {
+		s.key = record[in.key]
+	}
+	s.date = strings.Join(dates, " ")
+	if s.datum, err = strconv.ParseFloat(record[in.value], 64); err != nil {
+		// we had a float-parsing error
+		return s, fmt.Errorf("invalid float64 %q", record[in.value])
+	}
+	if s.at, err = in.times.parse(s.date); err != nil && in.timed {
+		return s, err
+	}
+	if in.sized {
+		s.size = in.size
+		if size := in.sizes; size < len(record) && record[size] != "" {
+			if s.size, err = strconv.ParseFloat(record[size], 64); err != nil {
+				return s, fmt.Errorf("invalid sample size %q", record[size])
+			}
+		}
+		if s.size <= 0 {
+			return s, fmt.Errorf("sample size must be > 0, not %g", s.size)
+		}
+	}
+	switch {
+	case in.counted && s.datum < 0:
+		return s, fmt.Errorf("count must be >= 0, not %g", s.datum)
+	case in.bounded && s.datum > s.size:
+		return s, fmt.Errorf("count %g is more than the sample size, %g", s.datum, s.size)
+	}
+	return s, nil
+}
+
+// malformed applies the policy to a bad line, returning an
+// error if we're to stop
+func (in *input) malformed(line int, record []string, err error) error {
+	lerr := &LineError{Line: line, Record: record, Err: err}
+	switch in.policy {
+	case PolicyAbort:
+		return lerr
+	case PolicySkip:
+		log.Printf("%v. Ignored.\n", lerr)
+	}
+	in.skipped++
+	return nil
+}
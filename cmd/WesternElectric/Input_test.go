@@ -0,0 +1,103 @@
+package WesternElectric
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Test_columns reads the same data in each of the delimiters, picking
+// out the columns by number and by name.
+func Test_columns(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		opts  Options
+		dates []string
+	}{
+		{
+			name:  "default",
+			data:  "#Time Sample\n10:20 344970\n10:30 222923\n",
+			opts:  Options{},
+			dates: []string{"10:20", "10:30"},
+		},
+		{
+			name:  "tab, as in example_A.csv",
+			data:  "#Time\tSample\n01/02/21 10:20 AM\t344970\n01/02/21 10:30 AM\t222923\n",
+			opts:  Options{Delimiter: "tab"},
+			dates: []string{"01/02/21 10:20 AM", "01/02/21 10:30 AM"},
+		},
+		{
+			name:  "tab, by name",
+			data:  "#Time\tSample\n01/02/21 10:20 AM\t344970\n01/02/21 10:30 AM\t222923\n",
+			opts:  Options{Delimiter: "tab", DateColumns: []string{"time"}, ValueColumn: "Sample"},
+			dates: []string{"01/02/21 10:20 AM", "01/02/21 10:30 AM"},
+		},
+		{
+			name:  "whitespace, with a date in three columns",
+			data:  "# comment\n01/02/21  10:20 AM \t 344970\n\n01/02/21 10:30 AM 222923\n",
+			opts:  Options{Delimiter: "whitespace", DateColumns: []string{"1", "2", "3"}, ValueColumn: "4"},
+			dates: []string{"01/02/21 10:20 AM", "01/02/21 10:30 AM"},
+		},
+		{
+			name:  "comma, from a log",
+			data:  "#host,when,status,bytes,latency\nweb1,10:20,200,512,344970\nweb1,10:30,200,640,222923\n",
+			opts:  Options{Delimiter: "comma", DateColumns: []string{"when"}, ValueColumn: "latency"},
+			dates: []string{"10:20", "10:30"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := newInput(strings.NewReader(tt.data), tt.opts)
+			if err != nil {
+				t.Fatalf("newInput() returned %v", err)
+			}
+			for i, datum := range []float64{344970, 222923} {
+				s, err := in.next()
+				if err != nil || s.date != tt.dates[i] || s.datum != datum {
+					t.Errorf("next() = %q %g, %v, expected %q %g", s.date, s.datum, err, tt.dates[i], datum)
+				}
+			}
+			if _, err := in.next(); err != io.EOF {
+				t.Errorf("next() returned %v at the end, expected EOF", err)
+			}
+		})
+	}
+}
+
+func Test_columnErrors(t *testing.T) {
+	const data = "#Time Sample\n10:20 344970\n"
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "unknown name", opts: Options{ValueColumn: "latency"}},
+		{name: "column 0", opts: Options{DateColumns: []string{"0"}}},
+		{name: "unknown delimiter", opts: Options{Delimiter: "pipe"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newInput(strings.NewReader(data), tt.opts); err == nil {
+				t.Errorf("newInput() returned no error")
+			}
+		})
+	}
+	if _, err := newInput(strings.NewReader("10:20 344970\n"), Options{ValueColumn: "Sample"}); err == nil {
+		t.Errorf("newInput() returned no error for a name, without a header")
+	}
+}
+
+// Test_headerLines checks errors still give the line in the file,
+// counting the header.
+func Test_headerLines(t *testing.T) {
+	in, err := newInput(strings.NewReader("#Time Sample\n10:20 1\n10:30 lots\n"), Options{Malformed: PolicyAbort})
+	if err != nil {
+		t.Fatalf("newInput() returned %v", err)
+	}
+	var lerr *LineError
+	_, _ = in.next()
+	if _, err := in.next(); !errors.As(err, &lerr) || lerr.Line != 3 {
+		t.Errorf("next() returned %v, expected an error at line 3", err)
+	}
+}
@@ -0,0 +1,175 @@
+package WesternElectric
+
+import (
+	"encoding/json"
+	"fmt"
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"io"
+	"os"
+	"time"
+)
+
+// Limits are fixed control limits. Classical SPC computes them from
+// a known-good baseline, in phase I, then freezes them to monitor new
+// data, in phase II, so a sustained shift can't become the new normal
+// the way it does with a moving average.
+type Limits struct {
+	Centre float64 `json:"centre"`        // the centre line, the mean of the baseline
+	Sigma  float64 `json:"sigma"`         // its standard deviation
+	N      int     `json:"n"`             // the number of points, or subgroups, in the baseline
+	From   string  `json:"from"`          // the datestamps of the first
+	To     string  `json:"to"`            // and last points in it
+	Key    string  `json:"key,omitempty"` // the series they're for, if the input has several
+}
+
+// LimitsDocument is a saved set of limits, with enough about where
+// they came from to reuse them on later runs, and on other machines.
+type LimitsDocument struct {
+	Version int `json:"version"`
+	Limits
+	Rules    []string  `json:"rules"`              // the rules they were meant for
+	NSamples int       `json:"nSamples"`           // the moving average in use at the time
+	Subgroup int       `json:"subgroup,omitempty"` // the size of the subgroups, if any
+	Spread   string    `json:"spread,omitempty"`   // and whether their range or sd was used
+	Source   string    `json:"source"`             // the file the baseline came from
+	Created  time.Time `json:"created"`
+}
+
+// limitsVersion is the version of the LimitsDocument we write
+const limitsVersion = 1
+
+// Upper returns the upper limit at n sigma
+func (l Limits) Upper(n float64) float64 {
+	return l.Centre + n*l.Sigma
+}
+
+// Lower returns the lower limit at n sigma
+func (l Limits) Lower(n float64) float64 {
+	return l.Centre - n*l.Sigma
+}
+
+func (l Limits) String() string {
+	if l.Key != "" {
+		return fmt.Sprintf("series %s, centre %0.4f sigma %0.4f from %d points, 3-sigma limits %0.4f to %0.4f",
+			l.Key, l.Centre, l.Sigma, l.N, l.Lower(3), l.Upper(3))
+	}
+	return fmt.Sprintf("centre %0.4f sigma %0.4f from %d points, 3-sigma limits %0.4f to %0.4f",
+		l.Centre, l.Sigma, l.N, l.Lower(3), l.Upper(3))
+}
+
+// ComputeLimits reads a known-good baseline, and computes control
+// limits from all of it, or from all its subgroups.
+func ComputeLimits(fp io.Reader, opts Options) (Limits, error) {
+	var l Limits
+	var subgroups *movingAverage.XBar
+
+	if opts.KeyColumn != "" {
+		return l, fmt.Errorf("a baseline is for a single series, not one with a key column")
+	}
+	add := newBaseline(opts)
+	if opts.Subgroup != 0 {
+		var err error
+		if subgroups, err = newXBar(opts, true); err != nil {
+			return l, err
+		}
+	}
+	in, err := newInput(fp, opts)
+	if err != nil {
+		return l, err
+	}
+	groups := newGrouper(opts)
+	for {
+		s, err := in.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return l, err
+		}
+		date := s.date
+		if groups != nil {
+			first, g, full := groups.add(s)
+			if !full {
+				continue
+			}
+			date = first.date
+			l.Centre, l.Sigma = subgroups.Add(g)
+		} else {
+			l.Centre, l.Sigma = add(s.datum)
+		}
+		l.N++
+		if l.N == 1 {
+			l.From = date
+		}
+		l.To = date
+	}
+	if l.N < 2 {
+		return l, fmt.Errorf("only %d points in the baseline, need at least 2", l.N)
+	}
+	return l, nil
+}
+
+// NewLimitsDocument describes limits computed with a set of options,
+// from a source file.
+func NewLimitsDocument(l Limits, opts Options, source string) LimitsDocument {
+	rules := opts.Rules
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	return LimitsDocument{
+		Version:  limitsVersion,
+		Limits:   l,
+		Rules:    RuleNames(rules),
+		NSamples: opts.NSamples,
+		Subgroup: opts.Subgroup,
+		Spread:   opts.Spread,
+		Source:   source,
+		Created:  time.Now().UTC(),
+	}
+}
+
+// WriteLimits writes a limits document, as indented json.
+func WriteLimits(w io.Writer, doc LimitsDocument) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(doc)
+}
+
+// ReadLimits reads a limits document written by WriteLimits.
+func ReadLimits(r io.Reader) (LimitsDocument, error) {
+	var doc LimitsDocument
+
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return doc, fmt.Errorf("can't read limits: %w", err)
+	}
+	if doc.Version != limitsVersion {
+		return doc, fmt.Errorf("limits are version %d, expected %d", doc.Version, limitsVersion)
+	}
+	if doc.Sigma < 0 || doc.N < 2 {
+		return doc, fmt.Errorf("limits have sigma %g from %d points, expected a baseline", doc.Sigma, doc.N)
+	}
+	return doc, nil
+}
+
+// SaveLimits writes a limits document to a file.
+func SaveLimits(filename string, doc LimitsDocument) error {
+	fp, err := os.Create(filename) //nolint
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", filename, err)
+	}
+	if err = WriteLimits(fp, doc); err != nil {
+		_ = fp.Close()
+		return fmt.Errorf("error writing %s: %w", filename, err)
+	}
+	return fp.Close()
+}
+
+// LoadLimits reads a limits document from a file.
+func LoadLimits(filename string) (LimitsDocument, error) {
+	fp, err := open(filename)
+	if err != nil {
+		return LimitsDocument{}, err
+	}
+	defer closeInput(fp, filename)
+	return ReadLimits(fp)
+}
@@ -0,0 +1,126 @@
+package WesternElectric
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// a steady baseline, then a shift of 5 that a moving average
+// would soon get used to
+const shifted = `#time value
+1 9
+2 11
+3 10
+4 9
+5 11
+6 10
+7 15
+8 15
+9 15
+10 15
+11 15
+12 15
+13 15
+14 15
+15 15
+16 15
+`
+
+func Test_computeLimits(t *testing.T) {
+	l, err := ComputeLimits(strings.NewReader("1 9\n2 11\n3 10\n4 9\n5 11\n6 10\n"), Options{})
+	if err != nil {
+		t.Fatalf("ComputeLimits() returned %v", err)
+	}
+	if l.N != 6 || l.Centre != 10 || math.Abs(l.Sigma-math.Sqrt(0.8)) > 1e-12 {
+		t.Errorf("ComputeLimits() = %v, expected centre 10, sigma 0.8944 from 6 points", l)
+	}
+	if _, err := ComputeLimits(strings.NewReader("1 9\n"), Options{}); err == nil {
+		t.Errorf("ComputeLimits() of one point returned no error")
+	}
+}
+
+func Test_phaseI(t *testing.T) {
+	var reported Limits
+
+	opts := Options{PhaseI: 6, OnLimits: func(l Limits) { reported = l }}
+	res, err := Analyze(strings.NewReader(shifted), opts)
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	if res.Limits == nil || reported != *res.Limits || reported.N != 6 {
+		t.Fatalf("Analyze() limits = %v, reported %v, expected 6 points", res.Limits, reported)
+	}
+	// every point after the baseline is judged, and they all stay out
+	if len(res.Events) != 10 {
+		t.Fatalf("Analyze() returned %d events, expected 10", len(res.Events))
+	}
+	last := res.Events[len(res.Events)-1]
+	if last.Mean != 10 || last.Indicator("ThreeSigma") != 3 || last.Indicator("NoSigma") != 9 {
+		t.Errorf("last event = %+v, expected ThreeSigma and NoSigma against a centre of 10", last)
+	}
+}
+
+func Test_phaseII(t *testing.T) {
+	limits := Limits{Centre: 10, Sigma: 1, N: 6}
+
+	res, err := Analyze(strings.NewReader(shifted), Options{Limits: &limits})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	if len(res.Events) != 16 || res.Events[0].Last() != 0 || res.Last != 9 {
+		t.Errorf("Analyze() returned %d events, last %d, expected 16 ending in 9", len(res.Events), res.Last)
+	}
+}
+
+func Test_phaseITooShort(t *testing.T) {
+	if _, err := Analyze(strings.NewReader("1 9\n2 11\n"), Options{PhaseI: 6}); err == nil {
+		t.Errorf("Analyze() of a short phase I returned no error")
+	}
+}
+
+func Test_limitsResiduals(t *testing.T) {
+	limits := Limits{Centre: 10, Sigma: 1, N: 6}
+
+	for _, opts := range []Options{
+		{Limits: &limits, Residuals: true},
+		{PhaseI: 6, Residuals: true},
+	} {
+		if _, err := Analyze(strings.NewReader(shifted), opts); err == nil {
+			t.Errorf("Analyze() of residuals from fixed limits returned no error")
+		}
+	}
+}
+
+func Test_limitsDocument(t *testing.T) {
+	var buf bytes.Buffer
+
+	l, err := ComputeLimits(strings.NewReader("1 9\n2 11\n3 10\n4 9\n5 11\n6 10\n"), Options{})
+	if err != nil {
+		t.Fatalf("ComputeLimits() returned %v", err)
+	}
+	if l.From != "1" || l.To != "6" {
+		t.Errorf("ComputeLimits() range = %q to %q, expected 1 to 6", l.From, l.To)
+	}
+	rules, _ := RuleSet("Nelson")
+	doc := NewLimitsDocument(l, Options{NSamples: 13, Rules: rules}, "example_B.csv")
+	if err = WriteLimits(&buf, doc); err != nil {
+		t.Fatalf("WriteLimits() returned %v", err)
+	}
+	got, err := ReadLimits(&buf)
+	if err != nil {
+		t.Fatalf("ReadLimits() returned %v", err)
+	}
+	if got.Limits != l || got.NSamples != 13 || got.Source != "example_B.csv" ||
+		len(got.Rules) != 8 || got.Rules[2] != "Trend" || !got.Created.Equal(doc.Created) {
+		t.Errorf("ReadLimits() = %+v, expected %+v", got, doc)
+	}
+
+	if _, err = ReadLimits(strings.NewReader(`{"version": 99}`)); err == nil {
+		t.Errorf("ReadLimits() of a future version returned no error")
+	}
+	if _, err = ReadLimits(strings.NewReader(`centre 10`)); err == nil {
+		t.Errorf("ReadLimits() of garbage returned no error")
+	}
+}
@@ -0,0 +1,52 @@
+package WesternElectric
+
+import (
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"math"
+)
+
+// MRChart is the moving-range half of an I-MR chart: it plots the range
+// between each datum and the one before, against an upper limit of D4
+// times the average range. The individuals half is the rules themselves,
+// comparing each datum to a moving average whose sigma is the average
+// range over d2, from movingAverage.NewMovingRange.
+type MRChart struct {
+	previous float64
+	started  bool
+}
+
+// NewMRChart creates a moving-range chart.
+func NewMRChart() *MRChart {
+	return &MRChart{}
+}
+
+func (c *MRChart) Name() string { return "MR" }
+
+func (c *MRChart) Columns() []string {
+	return []string{"mr", "mr+limit"}
+}
+
+// Prime remembers a datum that wasn't judged, so the first that is
+// has a range from it.
+func (c *MRChart) Prime(datum float64) {
+	c.previous, c.started = datum, true
+}
+
+// Add returns the moving range and its limit, recovering the average
+// range from the sigma, and signals 1 if the range is beyond the limit.
+// There's no lower limit, and no range for the first datum, unless
+// the chart was primed with the one before it.
+func (c *MRChart) Add(p Point) ([]float64, int) {
+	var mr float64
+	var rc int
+
+	limit := movingAverage.D4 * p.SD * movingAverage.D2
+	if c.started {
+		mr = math.Abs(p.Datum - c.previous)
+	}
+	c.previous, c.started = p.Datum, true
+	if mr > limit {
+		rc = 1
+	}
+	return []float64{mr, limit}, rc
+}
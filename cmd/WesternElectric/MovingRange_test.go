@@ -0,0 +1,72 @@
+package WesternElectric
+
+import (
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"math"
+	"strings"
+	"testing"
+)
+
+// Test_imr computes I-MR limits from ten points alternating 10 and 11,
+// so the average range is 1, then looks at a jump to 15 and back.
+func Test_imr(t *testing.T) {
+	const data = "1 10\n2 11\n3 10\n4 11\n5 10\n6 11\n7 10\n8 11\n9 10\n10 11\n11 11\n12 15\n13 11\n"
+	rules, _ := RuleSet("ThreeSigma")
+
+	res, err := Analyze(strings.NewReader(data), Options{PhaseI: 10, IMR: true, Rules: rules})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	if l := res.Limits; l.Centre != 10.5 || math.Abs(l.Sigma-1/movingAverage.D2) > 1e-12 {
+		t.Errorf("limits = %s, expected centre 10.5, sigma 1/d2", l)
+	}
+	tests := []struct {
+		date  string
+		mr    float64
+		rules int // ThreeSigma
+		mrc   int // the range rule
+	}{
+		{date: "11", mr: 0},
+		{date: "12", mr: 4, rules: 3, mrc: 1},
+		{date: "13", mr: 4, mrc: 1},
+	}
+	for i, tt := range tests {
+		e := res.Events[i]
+		if e.Date != tt.date || e.Charts[0] != tt.mr || math.Abs(e.Charts[1]-movingAverage.D4) > 1e-12 {
+			t.Errorf("%s: mr = %v, expected %s: %g against D4", e.Date, e.Charts, tt.date, tt.mr)
+		}
+		if e.Indicator("ThreeSigma") != tt.rules || e.Indicator("MR") != tt.mrc {
+			t.Errorf("%s: signals = %v, expected ThreeSigma %d, MR %d", e.Date, e.Signals, tt.rules, tt.mrc)
+		}
+	}
+}
+
+// Test_imrBoundary jumps on the first point judged, which the range from
+// the last point of the baseline, or of the warm-up, has to catch.
+func Test_imrBoundary(t *testing.T) {
+	const baseline = "1 10\n2 11\n3 10\n4 11\n5 10\n6 11\n7 10\n8 11\n9 10\n10 11\n"
+	rules, _ := RuleSet("ThreeSigma")
+	tests := []struct {
+		name string
+		data string
+		opts Options
+		mr   float64
+	}{
+		{name: "phase I", data: baseline + "11 20\n", opts: Options{PhaseI: 10, IMR: true, Rules: rules}, mr: 9},
+		{name: "warm-up", data: baseline[:25] + "6 20\n", opts: Options{NSamples: 5, IMR: true, Rules: rules}, mr: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Analyze(strings.NewReader(tt.data), tt.opts)
+			if err != nil {
+				t.Fatalf("Analyze() returned %v", err)
+			}
+			if len(res.Events) != 1 {
+				t.Fatalf("Analyze() returned %d events, expected 1", len(res.Events))
+			}
+			if e := res.Events[0]; e.Charts[0] != tt.mr || e.Indicator("MR") != 1 {
+				t.Errorf("%s: mr = %g, MR = %d, expected %g, 1", e.Date, e.Charts[0], e.Indicator("MR"), tt.mr)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package WesternElectric
+
+import (
+	"math"
+)
+
+/*
+ * The Nelson rules -- the four Western Electric rules, plus four more
+ * for trends, oscillation and stratification. In Nelson's order, they are
+ *	1. ThreeSigma, one point beyond 3 sigma
+ *	2. NoSigma, nine points in a row on the same side of the average
+ *	3. Trend, six points in a row steadily increasing or decreasing
+ *	4. Oscillation, fourteen points in a row alternating up and down
+ *	5. TwoSigma, two of three points beyond 2 sigma on the same side
+ *	6. OneSigma, four of five points beyond 1 sigma on the same side
+ *	7. Stratification, fifteen points in a row within 1 sigma
+ *	8. Mixture, eight points in a row beyond 1 sigma, on both sides
+ * See https://en.wikipedia.org/wiki/Nelson_rules
+ */
+
+type trend struct{}
+type oscillation struct{}
+type stratification struct{}
+type mixture struct{}
+
+func (trend) Name() string          { return "Trend" }
+func (trend) Window() int           { return 6 }
+func (oscillation) Name() string    { return "Oscillation" }
+func (oscillation) Window() int     { return 14 }
+func (stratification) Name() string { return "Stratification" }
+func (stratification) Window() int  { return 15 }
+func (mixture) Name() string        { return "Mixture" }
+func (mixture) Window() int         { return 8 }
+
+func init() {
+	for _, r := range []Rule{trend{}, oscillation{}, stratification{}, mixture{}} {
+		mustRegister(r)
+	}
+	mustRegisterSet("Nelson", "ThreeSigma", "NoSigma", "Trend", "Oscillation",
+		"TwoSigma", "OneSigma", "Stratification", "Mixture")
+}
+
+// Trend detects six points in a row, each higher (or each lower) than
+// the one before, for drifts. It returns 6 or -6.
+func (trend) Evaluate(history []Point) int {
+	window := moves(history, 5)
+	if nOf(window, 5) {
+		if window[0] == StateUp {
+			return 6
+		} else {
+			return -6
+		}
+	}
+	return 0
+}
+
+// Oscillation detects fourteen points in a row alternating up and
+// down, which suggests two interleaved sources or over-correction.
+// It has no direction, so it returns 14.
+func (oscillation) Evaluate(history []Point) int {
+	window := moves(history, 13)
+	for i := 0; i < len(window); i++ {
+		if window[i] == StateNA {
+			return 0
+		}
+		if i > 0 && window[i] == window[i-1] {
+			return 0
+		}
+	}
+	return 14
+}
+
+// Stratification detects fifteen points in a row within 1 sigma of the
+// average, which is less variation than we'd expect. It has no direction,
+// so it returns 15.
+func (stratification) Evaluate(history []Point) int {
+	window := states(history, 15, func(p Point) State {
+		if math.Abs(p.Datum-p.Average) < p.SD {
+			return StateWithin
+		}
+		return StateNA
+	})
+	if nOf(window, 15) {
+		return 15
+	}
+	return 0
+}
+
+// Mixture detects eight points in a row beyond 1 sigma, none within
+// it and some on each side, which suggests a mixture of two sources.
+// It has no direction, so it returns 8.
+func (mixture) Evaluate(history []Point) int {
+	var above, below int
+
+	window := states(history, 8, func(p Point) State {
+		switch {
+		case p.Datum > p.Average+p.SD:
+			return StateAbove
+		case p.Datum < p.Average-p.SD:
+			return StateBelow
+		default:
+			return StateNA
+		}
+	})
+	for _, s := range window {
+		switch s {
+		case StateAbove:
+			above++
+		case StateBelow:
+			below++
+		default:
+			return 0
+		}
+	}
+	if above > 0 && below > 0 {
+		return 8
+	}
+	return 0
+}
@@ -0,0 +1,172 @@
+package WesternElectric
+
+import (
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"testing"
+)
+
+func Test_nelson(t *testing.T) {
+	// each case is judged against average == 0, sd == 1, and as
+	// usual the last entry must be the one that reports
+	tests := []struct {
+		name   string
+		rule   string
+		data   []float64
+		expect int
+	}{
+		{
+			name:   "one beyond 3 sigma, below",
+			rule:   "ThreeSigma",
+			data:   []float64{-3.5},
+			expect: -3,
+		},
+		{
+			name:   "two of three beyond 2 sigma, below",
+			rule:   "TwoSigma",
+			data:   []float64{-2.5, 0, -2.5},
+			expect: -2,
+		},
+		{
+			name:   "two beyond 2 sigma, on opposite sides",
+			rule:   "TwoSigma",
+			data:   []float64{2.5, 0, -2.5},
+			expect: 0,
+		},
+		{
+			name:   "four of five beyond 1 sigma",
+			rule:   "OneSigma",
+			data:   []float64{1.5, 1.5, 0, 1.5, 1.5},
+			expect: 2,
+		},
+		{
+			name:   "four of five beyond 1 sigma, below",
+			rule:   "OneSigma",
+			data:   []float64{-1.5, -1.5, 0, -1.5, -1.5},
+			expect: -2,
+		},
+		{
+			name:   "two of five beyond 1 sigma",
+			rule:   "OneSigma",
+			data:   []float64{0, 0, 0, 1.5, 1.5},
+			expect: 0,
+		},
+		{
+			name:   "six increasing",
+			rule:   "Trend",
+			data:   []float64{-0.5, -0.4, -0.3, -0.2, -0.1, 0},
+			expect: 6,
+		},
+		{
+			name:   "six decreasing",
+			rule:   "Trend",
+			data:   []float64{9, 0.5, 0.4, 0.3, 0.2, 0.1, 0},
+			expect: -6,
+		},
+		{
+			name:   "five increasing",
+			rule:   "Trend",
+			data:   []float64{0.5, -0.4, -0.3, -0.2, -0.1, 0},
+			expect: 0,
+		},
+		{
+			name:   "a flat spot isn't a trend",
+			rule:   "Trend",
+			data:   []float64{-0.5, -0.4, -0.3, -0.3, -0.1, 0},
+			expect: 0,
+		},
+		{
+			name:   "fourteen alternating",
+			rule:   "Oscillation",
+			data:   []float64{0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1},
+			expect: 14,
+		},
+		{
+			name:   "thirteen alternating",
+			rule:   "Oscillation",
+			data:   []float64{0, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0},
+			expect: 0,
+		},
+		{
+			name:   "fifteen within 1 sigma",
+			rule:   "Stratification",
+			data:   []float64{0.1, -0.1, 0.2, -0.2, 0.1, -0.1, 0.2, -0.2, 0.1, -0.1, 0.2, -0.2, 0.1, -0.1, 0},
+			expect: 15,
+		},
+		{
+			name:   "fourteen within 1 sigma",
+			rule:   "Stratification",
+			data:   []float64{2, -0.1, 0.2, -0.2, 0.1, -0.1, 0.2, -0.2, 0.1, -0.1, 0.2, -0.2, 0.1, -0.1, 0},
+			expect: 0,
+		},
+		{
+			name:   "eight beyond 1 sigma on both sides",
+			rule:   "Mixture",
+			data:   []float64{2, -2, 2, -2, 1.5, -1.5, 2, -2},
+			expect: 8,
+		},
+		{
+			name:   "eight beyond 1 sigma on one side",
+			rule:   "Mixture",
+			data:   []float64{2, 2, 2, 2, 1.5, 1.5, 2, 2},
+			expect: 0,
+		},
+		{
+			name:   "one within 1 sigma",
+			rule:   "Mixture",
+			data:   []float64{2, -2, 2, -2, 0.5, -1.5, 2, -2},
+			expect: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got int
+
+			rules, err := RuleSet(tt.rule)
+			if err != nil {
+				t.Fatalf("RuleSet() returned %v", err)
+			}
+			d := NewDetector(movingAverage.Mock(1), 1, rules...)
+			for _, datum := range tt.data {
+				got = d.Judge(Point{Datum: datum, Average: 0, SD: 1}).Last()
+			}
+			if got != tt.expect {
+				t.Errorf("%s = %d, expected %d", tt.rule, got, tt.expect)
+			}
+		})
+	}
+}
+
+func Test_nelsonSet(t *testing.T) {
+	rules, err := RuleSet("Nelson")
+	if err != nil {
+		t.Fatalf("RuleSet() returned %v", err)
+	}
+	if len(rules) != 8 {
+		t.Errorf("Nelson has %d rules, expected 8", len(rules))
+	}
+}
+
+// Test_nelsonBelow checks the sigma rules take their side from the
+// average, not from the sign of the datum, with points 7 sigma below
+// an average of 100.
+func Test_nelsonBelow(t *testing.T) {
+	var v Verdict
+
+	rules, err := RuleSet("ThreeSigma,TwoSigma,OneSigma")
+	if err != nil {
+		t.Fatalf("RuleSet() returned %v", err)
+	}
+	d := NewDetector(movingAverage.Mock(1), 1, rules...)
+	for i := 0; i < 5; i++ {
+		v = d.Judge(Point{Datum: 93, Average: 100, SD: 1})
+	}
+	expect := map[string]int{"ThreeSigma": -3, "TwoSigma": -2, "OneSigma": -2}
+	if len(v.Signals) != len(expect) {
+		t.Fatalf("signals = %v, expected %v", v.Signals, expect)
+	}
+	for _, s := range v.Signals {
+		if s.Indicator != expect[s.Rule] {
+			t.Errorf("%s = %d, expected %d", s.Rule, s.Indicator, expect[s.Rule])
+		}
+	}
+}
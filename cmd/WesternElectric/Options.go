@@ -0,0 +1,203 @@
+package WesternElectric
+
+import (
+	"fmt"
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"time"
+)
+
+// Options are the settings for a run of the rules.
+type Options struct {
+	NSamples   int    // number of samples in the moving average, > 1
+	MinSamples int    // samples in the average before we start judging. Defaults to NSamples
+	Malformed  Policy // what to do with malformed lines
+	Rules      []Rule // the rules to apply, in order. Defaults to DefaultRules
+
+	// How to read the input: the Delimiter between fields, "space", the
+	// default, "tab", "comma" or "whitespace", for runs of spaces and tabs,
+	// and the columns holding the datestamp, joined with spaces if there
+	// are several, and the value. Columns are numbered from 1, as awk does,
+	// or named, as in the header line. They default to 1 and 2
+	Delimiter   string
+	DateColumns []string
+	ValueColumn string
+
+	// The column of a key, such as a host or endpoint, if the input has
+	// several series in it. Each gets its own detector, and its events
+	// are tagged with its key
+	KeyColumn string
+
+	// How to read the datestamps: a Go time layout, such as
+	// "2006-01-02 15:04:05", or "" to recognize RFC 3339, Unix seconds or
+	// milliseconds, "15:04" and "01/02/06 03:04 PM", read in Location,
+	// by default UTC. Events are reported with their time in TimeFormat,
+	// if it's set, rather than as they were read
+	TimeLayout string
+	Location   *time.Location
+	TimeFormat string
+
+	// Group the data into buckets of time, Bucket wide, and reduce each
+	// to a single datum with an Aggregate: "mean", the default, "sum",
+	// "count", "p50", "p95", "p99" or "max", before applying the rules
+	Bucket    time.Duration
+	Aggregate string
+
+	// The Interval the data are expected at, if they're to be checked
+	// for gaps, and what to do about any: see GapPolicy
+	Interval time.Duration
+	Gaps     GapPolicy
+
+	// The moving average to compare against: "simple", the default,
+	// "sliding", which is the same but faster for big windows, "median",
+	// which uses the median and MAD so outliers don't swamp it, or
+	// "ewma", which uses the smoothing factor Alpha, or "holtwinters",
+	// which forecasts each datum from the level, trend and season of
+	// Period samples, using Alpha, Beta and Gamma
+	Average string
+	Alpha   float64
+	Beta    float64
+	Gamma   float64
+	Period  int
+
+	// An I-MR chart: compare the data to a moving average with the
+	// moving-range estimate of sigma, instead of Average, and run a
+	// moving-range chart alongside the rules
+	IMR bool
+
+	// Subgroups of Subgroup consecutive data, whose means are compared
+	// to the X-bar chart's limits, from the average "range" or "sd"
+	// of the subgroups, as Spread says, with an R or S chart alongside.
+	// With a Bucket, the first Subgroup data in each bucket of time are
+	// its subgroup, instead of its Aggregate, and shorter buckets are dropped
+	Subgroup int
+	Spread   string
+
+	// An attribute chart, "p", "np", "c" or "u", for counts rather than
+	// measurements. The datum is the count, and the sample size is in
+	// SizeColumn, numbered or named like ValueColumn, by default the one
+	// after the value, or if a line doesn't have one, is SampleSize
+	Attribute  string
+	SizeColumn string
+	SampleSize float64
+
+	// Apply the rules to the residuals, datum - average, against an
+	// average of zero, rather than to the data themselves
+	Residuals bool
+
+	// Compare each datum to the same time of the "day" or "week",
+	// in slots of Slot, rather than to the data just before it.
+	// Each slot has its own moving average, of NSamples
+	Season string
+	Slot   time.Duration
+
+	// An EWMA control chart, run alongside the rules if Lambda is set
+	Lambda float64
+	L      float64
+
+	// A CUSUM chart, with its K and H in sigmas, run alongside the rules
+	CUSUM bool
+	K     float64
+	H     float64
+
+	// Fixed control limits, instead of a moving average. Either we're
+	// given Limits, or we compute them from the first PhaseI points.
+	Limits   *Limits
+	PhaseI   int
+	OnLimits func(l Limits) // called once phase I has computed the limits
+}
+
+// Policy is what to do with a malformed line: one that csv can't
+// parse, or that has too few fields or an invalid value.
+type Policy int32
+
+const (
+	PolicySkip  Policy = 0 // skip it, complaining in the log
+	PolicyCount Policy = 1 // skip it silently, just counting it
+	PolicyAbort Policy = 2 // stop, and return the error
+)
+
+var PolicyName = map[int32]string{
+	0: "skip",
+	1: "count",
+	2: "abort",
+}
+
+func (x Policy) String() string {
+	return PolicyName[int32(x)]
+}
+
+// ParsePolicy turns a policy name, as used on the command line, into a Policy.
+func ParsePolicy(name string) (Policy, error) {
+	for i, s := range PolicyName {
+		if s == name {
+			return Policy(i), nil
+		}
+	}
+	return PolicySkip, fmt.Errorf("unknown policy %q, expected skip, count or abort", name)
+}
+
+// newAverage creates the "add" function for the moving average
+// the options ask for.
+func newAverage(opts Options) (func(s float64) (float64, float64), error) {
+	if opts.IMR {
+		return movingAverage.NewMovingRange(opts.NSamples), nil
+	}
+	switch opts.Average {
+	case "", "simple":
+		return movingAverage.New(opts.NSamples), nil
+	case "sliding":
+		return movingAverage.NewSliding(opts.NSamples), nil
+	case "median":
+		return movingAverage.NewMedian(opts.NSamples), nil
+	case "ewma":
+		if opts.Alpha <= 0 || opts.Alpha > 1 {
+			return nil, fmt.Errorf("the ewma smoothing factor must be > 0 and <= 1, not %g", opts.Alpha)
+		}
+		return movingAverage.NewEWMA(opts.Alpha), nil
+	case "holtwinters":
+		for _, f := range []float64{opts.Alpha, opts.Beta, opts.Gamma} {
+			if f <= 0 || f > 1 {
+				return nil, fmt.Errorf("the holtwinters smoothing factors must be > 0 and <= 1, not %g", f)
+			}
+		}
+		if opts.Period < 2 {
+			return nil, fmt.Errorf("the holtwinters period must be > 1, not %d", opts.Period)
+		}
+		return movingAverage.NewHoltWinters(opts.Alpha, opts.Beta, opts.Gamma, opts.Period).Add, nil
+	}
+	return nil, fmt.Errorf("unknown moving average %q, expected simple, sliding, median, ewma or holtwinters", opts.Average)
+}
+
+// newBaseline creates the "add" function for computing fixed limits
+// from all of a baseline.
+func newBaseline(opts Options) func(s float64) (float64, float64) {
+	if opts.IMR {
+		return movingAverage.CumulativeMovingRange()
+	}
+	return movingAverage.Cumulative()
+}
+
+// newSeasonal creates the seasonal average the options ask for, with
+// a moving average of the usual kind for each of its slots.
+func newSeasonal(opts Options) (*movingAverage.Seasonal, error) {
+	var period time.Duration
+
+	switch opts.Season {
+	case "day":
+		period = movingAverage.Day
+	case "week":
+		period = movingAverage.Week
+	default:
+		return nil, fmt.Errorf("unknown season %q, expected day or week", opts.Season)
+	}
+	if opts.Slot <= 0 || period%opts.Slot != 0 {
+		return nil, fmt.Errorf("the seasonal slots must divide a %s evenly, not %s", opts.Season, opts.Slot)
+	}
+	if _, err := newAverage(opts); err != nil {
+		return nil, err
+	}
+	return movingAverage.NewSeasonal(period, opts.Slot, func() func(s float64) (float64, float64) {
+		add, _ := newAverage(opts) // already checked
+		return add
+	}), nil
+}
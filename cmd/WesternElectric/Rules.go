@@ -0,0 +1,256 @@
+package WesternElectric
+
+import (
+	"fmt"
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Point is a datum, and the average and standard deviation it
+// was compared against. If the datum is the mean of a subgroup,
+// the subgroup comes too.
+type Point struct {
+	Datum   float64
+	Average float64
+	SD      float64
+	Group   *movingAverage.Subgroup
+}
+
+// Rule is a test applied to the recent history of a series.
+type Rule interface {
+	// Name identifies the rule, in signals and on the command line.
+	Name() string
+	// Window is the number of points the rule looks at.
+	Window() int
+	// Evaluate looks at the history, newest first, and returns an
+	// indicator, such as 3 or -2, if the rule fired, or 0 if not.
+	// The history holds at least one point, but until the series
+	// has been running for a while it may be shorter than Window.
+	Evaluate(history []Point) int
+}
+
+// Graded is a rule that can say how serious its signals are. Rules
+// that aren't graded are taken as SeverityReject.
+type Graded interface {
+	Severity() Severity
+}
+
+// Severity is how serious a signal is: a rejection means the process
+// is out of control, a warning that it's worth a look.
+type Severity int32
+
+const (
+	SeverityReject Severity = 0
+	SeverityWarn   Severity = 1
+)
+
+var SeverityName = map[int32]string{
+	0: "reject",
+	1: "warn",
+}
+
+func (x Severity) String() string {
+	return SeverityName[int32(x)]
+}
+
+// severity returns how serious a rule's signals are
+func severity(r Rule) Severity {
+	if g, ok := r.(Graded); ok {
+		return g.Severity()
+	}
+	return SeverityReject
+}
+
+// the classic Western Electric rules
+type threeSigma struct{}
+type twoSigma struct{}
+type oneSigma struct{}
+type noSigma struct{}
+
+func (threeSigma) Name() string { return "ThreeSigma" }
+func (threeSigma) Window() int  { return 1 }
+func (twoSigma) Name() string   { return "TwoSigma" }
+func (twoSigma) Window() int    { return 3 }
+func (oneSigma) Name() string   { return "OneSigma" }
+func (oneSigma) Window() int    { return 5 }
+func (noSigma) Name() string    { return "NoSigma" }
+func (noSigma) Window() int     { return 9 }
+
+// RunRule detects Count out of Of points beyond Sigma standard
+// deviations, all on the same side of the average, the latest
+// included, such as "3 of 4 beyond 1.5 sigma". It returns
+// +Indicator or -Indicator.
+type RunRule struct {
+	Label     string
+	Count     int
+	Of        int
+	Sigma     float64
+	Indicator int
+}
+
+func (r RunRule) Name() string { return r.Label }
+func (r RunRule) Window() int  { return r.Of }
+
+func (r RunRule) Evaluate(history []Point) int {
+	window := states(history, r.Of, band(r.Sigma))
+	if nOf(window, r.Count) {
+		if window[0] == StateAbove {
+			return r.Indicator
+		} else {
+			return -r.Indicator
+		}
+	}
+	return 0
+}
+
+// band classifies points as above or below sigma standard deviations from
+// the average. With a sigma of 0, that's just above or below the average.
+func band(sigma float64) func(p Point) State {
+	return func(p Point) State {
+		switch {
+		case p.Datum > p.Average+(sigma*p.SD):
+			return StateAbove
+		case p.Datum < p.Average-(sigma*p.SD):
+			return StateBelow
+		default:
+			return StateNA
+		}
+	}
+}
+
+/*
+ * The registry of rules and rule sets, by name. It starts with the
+ * Western Electric rules, so the init functions of other rule sets
+ * can use them, whichever file they are in.
+ */
+var registry = struct {
+	sync.RWMutex
+	rules map[string]Rule
+	sets  map[string][]string
+}{
+	rules: map[string]Rule{
+		"ThreeSigma": threeSigma{},
+		"TwoSigma":   twoSigma{},
+		"OneSigma":   oneSigma{},
+		"NoSigma":    noSigma{},
+	},
+	sets: map[string][]string{
+		"WesternElectric": {"ThreeSigma", "TwoSigma", "OneSigma", "NoSigma"},
+	},
+}
+
+// Register adds a rule to the registry, so it can be asked for by name.
+func Register(r Rule) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	name := r.Name()
+	if _, ok := registry.rules[name]; ok {
+		return fmt.Errorf("rule %q is already registered", name)
+	}
+	if strings.ContainsAny(name, ", ") || name == "" {
+		return fmt.Errorf("rule name %q must be non-empty, without commas or spaces", name)
+	}
+	if r.Window() < 1 {
+		return fmt.Errorf("rule %q has a window of %d, expected at least 1", name, r.Window())
+	}
+	registry.rules[name] = r
+	return nil
+}
+
+// RegisterSet names an ordered set of registered rules, such as "WesternElectric".
+func RegisterSet(name string, rules ...string) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.sets[name]; ok {
+		return fmt.Errorf("rule set %q is already registered", name)
+	}
+	for _, r := range rules {
+		if _, ok := registry.rules[r]; !ok {
+			return fmt.Errorf("rule set %q uses unknown rule %q", name, r)
+		}
+	}
+	registry.sets[name] = rules
+	return nil
+}
+
+// Lookup finds a registered rule by name.
+func Lookup(name string) (Rule, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	r, ok := registry.rules[name]
+	return r, ok
+}
+
+// RuleSet turns a comma-separated list of rule and rule set names,
+// such as "WesternElectric" or "ThreeSigma,TwoSigma", into the
+// ordered rules they name.
+func RuleSet(spec string) ([]Rule, error) {
+	var rules []Rule
+
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if set, ok := registry.sets[name]; ok {
+			for _, r := range set {
+				rules = append(rules, registry.rules[r])
+			}
+			continue
+		}
+		r, ok := registry.rules[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown rule or rule set %q, expected one of %s",
+				name, strings.Join(names(), ", "))
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// RuleNames returns the names of a set of rules, in order.
+func RuleNames(rules []Rule) []string {
+	list := make([]string, len(rules))
+
+	for i, r := range rules {
+		list[i] = r.Name()
+	}
+	return list
+}
+
+// DefaultRules are the four classic Western Electric rules, in the order we apply them.
+func DefaultRules() []Rule {
+	rules, _ := RuleSet("WesternElectric")
+	return rules
+}
+
+// names lists the registered sets and rules, for error messages.
+// The caller holds the lock.
+func names() []string {
+	var list []string
+
+	for name := range registry.sets {
+		list = append(list, name)
+	}
+	for name := range registry.rules {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func mustRegister(r Rule) {
+	if err := Register(r); err != nil {
+		panic(err)
+	}
+}
+
+func mustRegisterSet(name string, rules ...string) {
+	if err := RegisterSet(name, rules...); err != nil {
+		panic(err)
+	}
+}
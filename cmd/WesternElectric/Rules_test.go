@@ -0,0 +1,77 @@
+package WesternElectric
+
+import (
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"testing"
+)
+
+func Test_runRule(t *testing.T) {
+	// a custom rule, 3 of 4 beyond 1.5 sigma. As usual, the last
+	// entry must be the one that reports
+	threeOfFour := RunRule{Label: "ThreeOfFour", Count: 3, Of: 4, Sigma: 1.5, Indicator: 4}
+	tests := []struct {
+		name   string
+		data   []float64 // judged against average == 0, sd == 1
+		expect int
+	}{
+		{name: "3 of 4 above", data: []float64{2, 0, 2, 2}, expect: 4},
+		{name: "3 of 4 below", data: []float64{-2, -2, 0, -2}, expect: -4},
+		{name: "2 of 4 above", data: []float64{2, 0, 0, 2}, expect: 0},
+		{name: "3 of 4, mixed sides", data: []float64{-2, 2, 0, 2}, expect: 0},
+		{name: "latest not beyond", data: []float64{2, 2, 2, 1}, expect: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got int
+
+			d := NewDetector(movingAverage.Mock(1), 1, threeOfFour)
+			for _, datum := range tt.data {
+				got = d.Judge(Point{Datum: datum, Average: 0, SD: 1}).Last()
+			}
+			if got != tt.expect {
+				t.Errorf("ThreeOfFour = %d, expected %d", got, tt.expect)
+			}
+		})
+	}
+}
+
+func Test_registry(t *testing.T) {
+	custom := RunRule{Label: "Test_registry", Count: 3, Of: 4, Sigma: 1.5, Indicator: 4}
+
+	if err := Register(custom); err != nil {
+		t.Fatalf("Register() returned %v", err)
+	}
+	if err := Register(custom); err == nil {
+		t.Errorf("Register() of a duplicate returned no error")
+	}
+	if err := Register(RunRule{Label: "has, comma", Of: 1}); err == nil {
+		t.Errorf("Register() of a bad name returned no error")
+	}
+	if r, ok := Lookup("Test_registry"); !ok || r.Window() != 4 {
+		t.Errorf("Lookup() = %v, %v, expected the custom rule", r, ok)
+	}
+
+	rules, err := RuleSet("WesternElectric, Test_registry")
+	if err != nil {
+		t.Fatalf("RuleSet() returned %v", err)
+	}
+	var got []string
+	for _, r := range rules {
+		got = append(got, r.Name())
+	}
+	expect := []string{"ThreeSigma", "TwoSigma", "OneSigma", "NoSigma", "Test_registry"}
+	if len(got) != len(expect) {
+		t.Fatalf("RuleSet() = %q, expected %q", got, expect)
+	}
+	for i := range got {
+		if got[i] != expect[i] {
+			t.Errorf("RuleSet() = %q, expected %q", got, expect)
+		}
+	}
+	if _, err := RuleSet("NoSuchRule"); err == nil {
+		t.Errorf("RuleSet() of an unknown rule returned no error")
+	}
+	if err := RegisterSet("Test_registry_set", "NoSuchRule"); err == nil {
+		t.Errorf("RegisterSet() with an unknown rule returned no error")
+	}
+}
@@ -0,0 +1,101 @@
+package WesternElectric
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// interleaved returns two series, one per host, on alternate lines,
+// with a spike in web2 that's normal for web1.
+func interleaved() string {
+	var sb strings.Builder
+
+	sb.WriteString("#time host latency\n")
+	for i := 0; i < 10; i++ {
+		web2 := 10 + i%3
+		if i == 9 {
+			web2 = 100
+		}
+		fmt.Fprintf(&sb, "10:%02d web1 %d\n", i, 100+i%3)
+		fmt.Fprintf(&sb, "10:%02d web2 %d\n", i, web2)
+	}
+	return sb.String()
+}
+
+func Test_series(t *testing.T) {
+	rules, _ := RuleSet("ThreeSigma")
+	res, err := Analyze(strings.NewReader(interleaved()),
+		Options{NSamples: 5, Rules: rules, KeyColumn: "host", ValueColumn: "latency"})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	// five each to fill their averages
+	if len(res.Events) != 10 {
+		t.Fatalf("Analyze() returned %d events, expected 10", len(res.Events))
+	}
+	for _, e := range res.Events {
+		switch {
+		case e.Key == "web1" && (e.Mean < 100 || e.Anomalous()):
+			t.Errorf("web1 at %s was %v against %g, expected nothing against its own average", e.Date, e.Signals, e.Mean)
+		case e.Key == "web2" && e.Mean > 12:
+			t.Errorf("web2 at %s was compared to %g, expected its own average", e.Date, e.Mean)
+		case e.Key == "web2" && e.Date == "10:09" && e.Indicator("ThreeSigma") != 3:
+			t.Errorf("web2's spike was %v, expected ThreeSigma 3", e.Signals)
+		case e.Key != "web1" && e.Key != "web2":
+			t.Errorf("event at %s was for series %q", e.Date, e.Key)
+		}
+	}
+
+	if _, err := Analyze(strings.NewReader(interleaved()), Options{NSamples: 5, KeyColumn: "datacenter"}); err == nil {
+		t.Errorf("Analyze() returned no error for an unknown key column")
+	}
+}
+
+// Test_seriesPhaseI computes limits for each series.
+func Test_seriesPhaseI(t *testing.T) {
+	var limits []Limits
+
+	_, err := Analyze(strings.NewReader(interleaved()), Options{PhaseI: 5, KeyColumn: "2", ValueColumn: "3",
+		OnLimits: func(l Limits) { limits = append(limits, l) }})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	if len(limits) != 2 || limits[0].Key != "web1" || limits[1].Key != "web2" || limits[1].Centre != 10.8 {
+		t.Errorf("limits were %v, expected web1's and web2's, centred at 10.8", limits)
+	}
+
+	// a series too short for its limits is left out, not an error
+	res, err := Analyze(strings.NewReader(interleaved()+"10:10 web3 1\n"), Options{PhaseI: 5, KeyColumn: "2", ValueColumn: "3"})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	if len(res.Unjudged) != 1 || res.Unjudged[0] != "web3" || len(res.Events) == 0 {
+		t.Errorf("Analyze() left out %q, with %d events, expected just web3, and events for the rest",
+			res.Unjudged, len(res.Events))
+	}
+}
+
+// Test_seriesBuckets buckets each series separately.
+func Test_seriesBuckets(t *testing.T) {
+	const data = "10:01 a 1\n10:02 b 10\n10:04 a 3\n10:12 b 20\n10:13 a 5\n10:14 b 30\n"
+
+	in, err := newInput(strings.NewReader(data), Options{KeyColumn: "2", ValueColumn: "3",
+		Bucket: 10 * time.Minute, Aggregate: "sum"})
+	if err != nil {
+		t.Fatalf("newInput() returned %v", err)
+	}
+	for _, expect := range []sample{
+		{key: "b", date: "10:00", datum: 10},
+		{key: "a", date: "10:00", datum: 4},
+		{key: "a", date: "10:10", datum: 5},
+		{key: "b", date: "10:10", datum: 50},
+	} {
+		s, err := in.next()
+		if err != nil || s.key != expect.key || s.date != expect.date || s.datum != expect.datum {
+			t.Errorf("next() = %s %q %g, %v, expected %s %q %g",
+				s.key, s.date, s.datum, err, expect.key, expect.date, expect.datum)
+		}
+	}
+}
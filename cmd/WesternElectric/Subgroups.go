@@ -0,0 +1,125 @@
+package WesternElectric
+
+import (
+	"fmt"
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"strings"
+)
+
+// SpreadChart is the R or S half of an X-bar chart: it plots the range or
+// standard deviation of each subgroup, between limits of D3 and D4 times
+// the average range, or B3 and B4 times the average standard deviation.
+// The X-bar half is the rules themselves, comparing the subgroup means
+// to the centre line and sigma from movingAverage.XBar.
+type SpreadChart struct {
+	x *movingAverage.XBar // just for its constants
+}
+
+// NewSpreadChart creates an R chart for subgroups of size, or an S
+// chart if s is true.
+func NewSpreadChart(size int, s bool) (*SpreadChart, error) {
+	x, err := movingAverage.NewXBar(size, s, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SpreadChart{x: x}, nil
+}
+
+func (c *SpreadChart) Name() string {
+	if c.x.S {
+		return "S"
+	}
+	return "R"
+}
+
+func (c *SpreadChart) Columns() []string {
+	name := strings.ToLower(c.Name())
+	return []string{name, name + "-limit", name + "+limit"}
+}
+
+// Add returns the subgroup's spread and its limits, recovering the
+// average spread from the sigma of the means, and signals 1 if the
+// spread is above the upper limit, -1 if below the lower.
+func (c *SpreadChart) Add(p Point) ([]float64, int) {
+	var spread float64
+	var rc int
+
+	_, lower, upper := c.x.SpreadLimits(p.SD)
+	if p.Group != nil {
+		spread = c.x.Spread(*p.Group)
+	}
+	switch {
+	case spread > upper:
+		rc = 1
+	case spread < lower:
+		rc = -1
+	}
+	return []float64{spread, lower, upper}, rc
+}
+
+// grouper collects consecutive data into subgroups, or takes them from
+// buckets of time, if the input is bucketed.
+type grouper struct {
+	size  int
+	data  []float64
+	first sample
+}
+
+// newGrouper creates a grouper for the subgroups the options ask for,
+// or returns nil if they don't.
+func newGrouper(opts Options) *grouper {
+	if opts.Subgroup == 0 {
+		return nil
+	}
+	return &grouper{size: opts.Subgroup, data: make([]float64, 0, opts.Subgroup)}
+}
+
+// add adds a sample, and once the subgroup is full, returns it with
+// its first sample, for the date, and starts the next. A bucket is a
+// subgroup by itself, of its first size data, and is dropped if it
+// doesn't have that many.
+func (g *grouper) add(s sample) (sample, movingAverage.Subgroup, bool) {
+	if s.group != nil {
+		if len(s.group) < g.size {
+			return sample{}, movingAverage.Subgroup{}, false
+		}
+		return s, movingAverage.Summarize(s.group[:g.size]), true
+	}
+	if len(g.data) == 0 {
+		g.first = s
+	}
+	g.data = append(g.data, s.datum)
+	if len(g.data) < g.size {
+		return sample{}, movingAverage.Subgroup{}, false
+	}
+	sg := movingAverage.Summarize(g.data)
+	g.data = g.data[:0]
+	return g.first, sg, true
+}
+
+// newXBar creates the X-bar average the options ask for, either
+// with the moving average for both the means and the spreads, or
+// cumulatively, for computing fixed limits.
+func newXBar(opts Options, cumulative bool) (*movingAverage.XBar, error) {
+	var s bool
+
+	switch opts.Spread {
+	case "", "range":
+	case "sd":
+		s = true
+	default:
+		return nil, fmt.Errorf("unknown spread %q, expected range or sd", opts.Spread)
+	}
+	if opts.IMR || opts.Season != "" {
+		return nil, fmt.Errorf("subgroups can't be used with an I-MR chart or a season")
+	}
+	if cumulative {
+		return movingAverage.NewXBar(opts.Subgroup, s, movingAverage.Cumulative(), movingAverage.Cumulative())
+	}
+	means, err := newAverage(opts)
+	if err != nil {
+		return nil, err
+	}
+	spreads, _ := newAverage(opts) // already checked
+	return movingAverage.NewXBar(opts.Subgroup, s, means, spreads)
+}
@@ -0,0 +1,124 @@
+package WesternElectric
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// subgrouped returns ten in-control subgroups of five, each with a mean
+// of 3 and a range of 4, then a shifted subgroup and a widely spread one.
+func subgrouped() string {
+	var sb strings.Builder
+	var i int
+
+	line := func(x float64) {
+		i++
+		fmt.Fprintf(&sb, "%d %g\n", i, x)
+	}
+	for g := 0; g < 10; g++ {
+		for k := 0; k < 5; k++ {
+			line(float64(1 + (k+g)%5))
+		}
+	}
+	for _, x := range []float64{6, 7, 8, 9, 10, -10, 3, 3, 3, 16, 1, 2} {
+		line(x)
+	}
+	return sb.String()
+}
+
+func Test_subgroups(t *testing.T) {
+	rules, _ := RuleSet("ThreeSigma")
+	tests := []struct {
+		name   string
+		spread string
+		sigma  float64
+		values []float64 // the spread chart's, for the shifted subgroup
+	}{
+		{name: "X-bar/R", spread: "range", sigma: 0.577 * 4 / 3, values: []float64{4, 0, 2.114 * 4}},
+		{name: "X-bar/S", spread: "sd", sigma: 1.427 * math.Sqrt(2.5) / 3,
+			values: []float64{math.Sqrt(2.5), 0, 2.089 * math.Sqrt(2.5)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Analyze(strings.NewReader(subgrouped()),
+				Options{PhaseI: 10, Subgroup: 5, Spread: tt.spread, Rules: rules})
+			if err != nil {
+				t.Fatalf("Analyze() returned %v", err)
+			}
+			if l := res.Limits; l.Centre != 3 || math.Abs(l.Sigma-tt.sigma) > 1e-12 || l.N != 10 || l.To != "46" {
+				t.Errorf("limits = %s, to %s, expected centre 3, sigma %g from 10 subgroups, to 46", l, l.To, tt.sigma)
+			}
+			// the last two points don't make a subgroup
+			if len(res.Events) != 2 {
+				t.Fatalf("Analyze() returned %d events, expected 2", len(res.Events))
+			}
+			shifted, spread := res.Events[0], res.Events[1]
+			if shifted.Date != "51" || shifted.Datum != 8 || shifted.Indicator("ThreeSigma") != 3 {
+				t.Errorf("shifted subgroup %s: %g, %v, expected 51: 8, ThreeSigma 3", shifted.Date, shifted.Datum, shifted.Signals)
+			}
+			for i, x := range tt.values {
+				if math.Abs(shifted.Charts[i]-x) > 1e-12 {
+					t.Errorf("shifted subgroup's spread chart = %v, expected %v", shifted.Charts, tt.values)
+					break
+				}
+			}
+			if spread.Datum != 3 || spread.Indicator("ThreeSigma") != 0 || spread.Indicator(strings.ToUpper(tt.spread[:1])) != 1 {
+				t.Errorf("spread subgroup %g, %v, expected 3, just a spread signal", spread.Datum, spread.Signals)
+			}
+		})
+	}
+
+	for _, opts := range []Options{
+		{NSamples: 5, Subgroup: 5, Spread: "iqr"},
+		{NSamples: 5, Subgroup: 1},
+		{NSamples: 5, Subgroup: 5, IMR: true},
+	} {
+		if _, err := Analyze(strings.NewReader(subgrouped()), opts); err == nil {
+			t.Errorf("Analyze() returned no error for %+v", opts)
+		}
+	}
+}
+
+// Test_bucketSubgroups takes a subgroup of three from each ten minutes,
+// however many points are in it, and drops the ten minutes with too few.
+func Test_bucketSubgroups(t *testing.T) {
+	var sb strings.Builder
+
+	for m := 0; m < 60; m++ {
+		switch {
+		case m >= 30 && m < 40 && m > 31:
+			// only two points, from 10:30 and 10:31
+		case m >= 50:
+			fmt.Fprintf(&sb, "10:%02d %d\n", m, 20+m%3)
+		default:
+			fmt.Fprintf(&sb, "10:%02d %d\n", m, 10+m%3)
+		}
+	}
+	rules, _ := RuleSet("ThreeSigma")
+	res, err := Analyze(strings.NewReader(sb.String()), Options{NSamples: 3, Subgroup: 3,
+		Bucket: 10 * time.Minute, Aggregate: "max", Rules: rules})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	// 10:00, 10:10 and 10:20 fill the average, and 10:30 is dropped
+	if len(res.Events) != 2 {
+		t.Fatalf("Analyze() returned %d events, expected 2", len(res.Events))
+	}
+	for i, expect := range []struct {
+		date string
+		mean float64
+		rc   int
+	}{
+		{date: "10:40", mean: 11},
+		{date: "10:50", mean: 21, rc: 3},
+	} {
+		e := res.Events[i]
+		if e.Date != expect.date || e.Datum != expect.mean || e.Mean != 11 || e.Indicator("ThreeSigma") != expect.rc {
+			t.Errorf("event %s was %g against %g, %v, expected %s %g against 11, ThreeSigma %d",
+				e.Date, e.Datum, e.Mean, e.Signals, expect.date, expect.mean, expect.rc)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package WesternElectric
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the forms of datestamp we recognize, such as the
+// "10:20" of example.csv and the "01/02/21 10:20 AM" of example_A.csv.
+var timeLayouts = []string{
+	"15:04",
+	"15:04:05",
+	"01/02/06 03:04 PM",
+	"01/02/06 15:04",
+	time.RFC3339,
+}
+
+// unixMillis is the smallest Unix time we take to be in milliseconds:
+// as seconds, it's in the year 5138, and as milliseconds, in 1973.
+const unixMillis = 1e11
+
+// timeParser turns datestamps into times, using a layout it was given,
+// or else recognizing them, in a time zone.
+type timeParser struct {
+	layout string         // a Go time layout, or "" to recognize them
+	loc    *time.Location // the time zone of datestamps that don't have one
+	last   int            // the layout that worked last, to try first
+	unix   bool           // or if the last was a Unix time
+}
+
+// newTimeParser creates a parser for the layout and time zone the options
+// ask for, by default recognizing the layout, in UTC.
+func newTimeParser(opts Options) *timeParser {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &timeParser{layout: opts.TimeLayout, loc: loc}
+}
+
+// parse turns a datestamp into a time.
+func (p *timeParser) parse(s string) (time.Time, error) {
+	if p.layout != "" {
+		t, err := time.ParseInLocation(p.layout, s, p.loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q, expected %q", s, p.layout)
+		}
+		return clock(t), nil
+	}
+	if t, ok := p.parseUnix(s); ok {
+		p.unix = true
+		return t, nil
+	}
+	p.unix = false
+	// a file almost always uses one layout throughout
+	if t, err := time.ParseInLocation(timeLayouts[p.last], s, p.loc); err == nil {
+		return clock(t), nil
+	}
+	for i, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, p.loc); err == nil {
+			p.last = i
+			return clock(t), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// clock moves a time read without a date into UTC, at the same time
+// on the clock. In year 0, where those land, zones such as
+// America/Toronto are on local mean time, five hours, 17 minutes and
+// 32 seconds behind, which would show when they're printed in a zone
+// or bucketed. Without a date, the zone doesn't matter anyway.
+func clock(t time.Time) time.Time {
+	if !dayless(t) {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// parseUnix recognizes a Unix time, in seconds, perhaps with a fraction,
+// or in milliseconds if it's too big to be seconds.
+func (p *timeParser) parseUnix(s string) (time.Time, bool) {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }) >= 0 {
+		return time.Time{}, false
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if x >= unixMillis {
+		return time.UnixMilli(int64(x)).In(p.loc), true
+	}
+	sec, frac := math.Modf(x)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).In(p.loc), true
+}
+
+// format turns a time back into a datestamp, in the layout the last one
+// was read in, or in Unix seconds if it was one. Times without a date
+// stay in UTC, as clock left them.
+func (p *timeParser) format(t time.Time) string {
+	loc := p.loc
+	if dayless(t) {
+		loc = time.UTC
+	}
+	switch {
+	case p.layout != "":
+		return t.In(loc).Format(p.layout)
+	case p.unix:
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.In(loc).Format(timeLayouts[p.last])
+}
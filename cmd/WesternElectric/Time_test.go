@@ -0,0 +1,109 @@
+package WesternElectric
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseTime(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	tests := []struct {
+		name   string
+		date   string
+		layout string
+		loc    *time.Location
+		expect time.Time
+		fail   bool
+	}{
+		{name: "RFC 3339", date: "2021-01-02T10:20:00-05:00",
+			expect: time.Date(2021, 1, 2, 15, 20, 0, 0, time.UTC)},
+		{name: "unix seconds", date: "1609582800",
+			expect: time.Date(2021, 1, 2, 10, 20, 0, 0, time.UTC)},
+		{name: "unix seconds, with a fraction", date: "1609582800.5",
+			expect: time.Date(2021, 1, 2, 10, 20, 0, 5e8, time.UTC)},
+		{name: "unix milliseconds", date: "1609582800250",
+			expect: time.Date(2021, 1, 2, 10, 20, 0, 25e7, time.UTC)},
+		{name: "example.csv", date: "10:20",
+			expect: time.Date(0, 1, 1, 10, 20, 0, 0, time.UTC)},
+		{name: "example_A.csv", date: "01/02/21 10:20 PM",
+			expect: time.Date(2021, 1, 2, 22, 20, 0, 0, time.UTC)},
+		{name: "in a time zone", date: "01/02/21 10:20 AM", loc: toronto,
+			expect: time.Date(2021, 1, 2, 15, 20, 0, 0, time.UTC)},
+		{name: "no date, in a time zone", date: "10:20", loc: toronto,
+			expect: time.Date(0, 1, 1, 10, 20, 0, 0, time.UTC)},
+		{name: "no date, in a time zone, with a layout", date: "10:20:30", layout: "15:04:05", loc: toronto,
+			expect: time.Date(0, 1, 1, 10, 20, 30, 0, time.UTC)},
+		{name: "unix seconds, in a time zone", date: "1609582800", loc: toronto,
+			expect: time.Date(2021, 1, 2, 10, 20, 0, 0, time.UTC)},
+		{name: "a layout", date: "2021-01-02 10:20", layout: "2006-01-02 15:04",
+			expect: time.Date(2021, 1, 2, 10, 20, 0, 0, time.UTC)},
+		{name: "a layout that doesn't match", date: "10:20", layout: "2006-01-02 15:04", fail: true},
+		{name: "not a time", date: "noon", fail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTimeParser(Options{TimeLayout: tt.layout, Location: tt.loc}).parse(tt.date)
+			switch {
+			case tt.fail && err == nil:
+				t.Errorf("parse(%q) = %v, expected an error", tt.date, got)
+			case !tt.fail && err != nil:
+				t.Errorf("parse(%q) returned %v", tt.date, err)
+			case !tt.fail && !got.Equal(tt.expect):
+				t.Errorf("parse(%q) = %v, expected %v", tt.date, got, tt.expect)
+			}
+		})
+	}
+}
+
+// Test_eventTimes carries the times through to the events, and reports
+// them in a layout, or as they were read.
+func Test_eventTimes(t *testing.T) {
+	const data = "1609582800 1\n1609583400 2\n1609584000 3\n1609584600 99\n"
+
+	res, err := Analyze(strings.NewReader(data), Options{NSamples: 3})
+	if err != nil {
+		t.Fatalf("Analyze() returned %v", err)
+	}
+	last := res.Events[len(res.Events)-1]
+	if expect := time.Date(2021, 1, 2, 10, 50, 0, 0, time.UTC); !last.Time.Equal(expect) {
+		t.Errorf("last event was at %v, expected %v", last.Time, expect)
+	}
+	if when := last.When("15:04"); when != "10:50" {
+		t.Errorf("When(\"15:04\") = %q, expected \"10:50\"", when)
+	}
+	if when := last.When(""); when != "1609584600" {
+		t.Errorf("When(\"\") = %q, expected it as read", when)
+	}
+
+	// without a layout, dates that aren't times are fine
+	res, err = Analyze(strings.NewReader(spike), Options{NSamples: 5})
+	if err != nil || res.Skipped != 0 {
+		t.Errorf("Analyze() skipped %d, returned %v", res.Skipped, err)
+	}
+	// but with one, they're malformed
+	res, _ = Analyze(strings.NewReader(spike), Options{NSamples: 5, TimeLayout: time.RFC3339})
+	if res.Skipped != 9 {
+		t.Errorf("Analyze() skipped %d lines, expected all 9", res.Skipped)
+	}
+
+	// times without dates keep their clock, whatever the time zone
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	p := newTimeParser(Options{Location: toronto})
+	at, err := p.parse("10:20")
+	if err != nil {
+		t.Fatalf("parse(\"10:20\") returned %v", err)
+	}
+	if got := p.format(at); got != "10:20" {
+		t.Errorf("format() = %q, expected \"10:20\"", got)
+	}
+	if got := (Event{Date: "10:20", Time: at}).When("15:04 Z07:00"); got != "10:20 Z" {
+		t.Errorf("When() = %q, expected \"10:20 Z\"", got)
+	}
+}
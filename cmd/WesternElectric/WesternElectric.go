@@ -1,32 +1,54 @@
 package WesternElectric
 
 import (
+	"fmt"
 	"log"
 	"os"
 )
 
 // ApplyRules applies the Western Electric rules to a stream of data, using a
-// moving average of nSamples as the thing to compare against.
-func ApplyRules(filename string, nSamples, reporting int) int {
-	var fp *os.File
-	var err error
+// moving average of opts.NSamples as the thing to compare against.
+func ApplyRules(filename string, opts Options, reporting int) (Result, error) {
+	fp, err := open(filename)
+	if err != nil {
+		return Result{}, err
+	}
+	defer closeInput(fp, filename)
+	return Worker(fp, opts, reporting)
+}
+
+// BaselineLimits computes phase I control limits from a file of
+// known-good data, to use in place of the moving average.
+func BaselineLimits(filename string, opts Options) (Limits, error) {
+	fp, err := open(filename)
+	if err != nil {
+		return Limits{}, err
+	}
+	defer closeInput(fp, filename)
+	return ComputeLimits(fp, opts)
+}
 
+// open opens an input file, or stdin if the filename is "-"
+func open(filename string) (*os.File, error) {
 	if filename == "-" {
 		// if the filename is "-", read stdin
-		fp = os.Stdin
-	} else {
-		fp, err = os.Open(filename) //nolint
-		if err != nil {
-			log.Fatalf("error opening %s: %q, halting.", filename, err)
-		}
-		defer func() {
-			err := fp.Close()
-			if err != nil {
-				log.Printf("Close of input file %q failed, ignored. %v\n",
-					filename, err)
-			}
-		}()
+		return os.Stdin, nil
+	}
+	fp, err := os.Open(filename) //nolint
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", filename, err)
+	}
+	return fp, nil
+}
+
+// closeInput closes a file we opened, but not stdin
+func closeInput(fp *os.File, filename string) {
+	if fp == os.Stdin {
+		return
+	}
+	err := fp.Close()
+	if err != nil {
+		log.Printf("Close of input file %q failed, ignored. %v\n",
+			filename, err)
 	}
-	rc := Worker(fp, nSamples, reporting)
-	return rc
 }
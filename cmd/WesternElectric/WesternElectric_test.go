@@ -117,11 +117,13 @@ func Test_twoSigma(t *testing.T) {
 			} else {
 				add = movingAverage.New(tt.nSamples)
 			}
+			rules, _ := RuleSet("TwoSigma")
+			d := NewDetector(add, tt.nSamples, rules...)
 			t.Logf("n:  flag  data av   sd   a+3sd\n")
 			for i, datum = range tt.data {
 				if i > tt.nSamples {
 					// once we have an average, start looking
-					got = TwoSigma(datum, average, sd)
+					got = d.Judge(Point{Datum: datum, Average: average, SD: sd}).Last()
 					t.Logf("%-0.2d: %-5.5q %-4.2g %-4.2g %-4.2g %-4.2g\n", i, got, datum, oldAv, oldSD, oldAv+3*oldSD)
 					oldAv, oldSD = average, sd
 				}
@@ -136,6 +138,63 @@ func Test_twoSigma(t *testing.T) {
 	}
 }
 
+func Test_noSigma(t *testing.T) {
+	// noSigma detects 9 out of 9 points on the same side of the average.
+	// As above, the last entry MUST be the one that reports. Each case
+	// feeds at least nine samples, to fill the window.
+	tests := []struct {
+		name     string
+		data     []float64 // last value must be the outlier
+		nSamples int
+		expect   int
+	}{
+		{
+			name:     "9 above",
+			data:     []float64{1, 1, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5},
+			nSamples: 1,
+			expect:   9,
+		},
+		{
+			name:     "9 below",
+			data:     []float64{1, 1, -0.5, -0.5, -0.5, -0.5, -0.5, -0.5, -0.5, -0.5, -0.5},
+			nSamples: 1,
+			expect:   -9,
+		},
+		{
+			name:     "8 above",
+			data:     []float64{1, 1, -0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5},
+			nSamples: 1,
+			expect:   0,
+		},
+		{
+			name:     "9 on the average",
+			data:     []float64{1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			nSamples: 1,
+			expect:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var average, sd float64
+			var got int
+
+			// use average == 0, sd == 1
+			add := movingAverage.Mock(tt.nSamples)
+			rules, _ := RuleSet("NoSigma")
+			d := NewDetector(add, tt.nSamples, rules...)
+			for i, datum := range tt.data {
+				if i > tt.nSamples {
+					got = d.Judge(Point{Datum: datum, Average: average, SD: sd}).Last()
+				}
+				average, sd = add(datum)
+			}
+			if got != tt.expect {
+				t.Errorf("noSigma() = %d, expected %d", got, tt.expect)
+			}
+		})
+	}
+}
+
 // TestShiftRight looks to see if shiftRight(1,2,3) = 0,1,2
 // where the leftmost value is zero-filled.
 func Test_shiftRight(t *testing.T) {
@@ -162,13 +221,16 @@ func Test_westernElectric(t *testing.T) {
 			name:     "example.csv", // FIXME, breake up into sets
 			file:     "./testdata/example.csv",
 			nSamples: 5,
-			expect:   2,
+			expect:   -2, // the last point is far below the average
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rc := ApplyRules(tt.file, tt.nSamples, 0)
-			if rc != tt.expect {
+			res, err := ApplyRules(tt.file, Options{NSamples: tt.nSamples}, 0)
+			if err != nil {
+				t.Fatalf("ApplyRules() returned %v", err)
+			}
+			if res.Last != tt.expect {
 				t.Errorf("we found a failure\n")
 			}
 
@@ -177,8 +239,11 @@ func Test_westernElectric(t *testing.T) {
 }
 
 func ExampleWesternElectric() {
-	rc := ApplyRules("./testdata/example.csv", 5, 0)
-	if rc > 0 {
+	res, err := ApplyRules("./testdata/example.csv", Options{NSamples: 5}, 0)
+	if err != nil {
+		log.Printf("we couldn't read the data: %v\n", err)
+	}
+	if res.Last > 0 {
 		log.Printf("we found at least one failure\n")
 	}
 
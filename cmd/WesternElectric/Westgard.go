@@ -0,0 +1,65 @@
+package WesternElectric
+
+/*
+ * The Westgard rules, for laboratory quality control. They use the same
+ * bands as the Western Electric rules, but 1-2s is only a warning, and
+ * the rest reject the run. In the usual notation, they are
+ *	1-2s, one point beyond 2 sigma: a warning
+ *	1-3s, one point beyond 3 sigma
+ *	2-2s, two points in a row beyond 2 sigma on the same side
+ *	R-4s, two points in a row beyond 2 sigma on opposite sides, a range over 4 sigma
+ *	4-1s, four points in a row beyond 1 sigma on the same side
+ *	10x,  ten points in a row on the same side of the average
+ * See https://www.westgard.com/mltirule.htm
+ */
+
+// westgard is a run rule, graded as a warning or a rejection.
+type westgard struct {
+	RunRule
+	severity Severity
+}
+
+func (w westgard) Severity() Severity { return w.severity }
+
+// inARow is a Westgard rule for n points in a row beyond sigma,
+// all on the same side.
+func inARow(name string, n int, sigma float64, indicator int, severity Severity) westgard {
+	return westgard{
+		RunRule:  RunRule{Label: name, Count: n, Of: n, Sigma: sigma, Indicator: indicator},
+		severity: severity,
+	}
+}
+
+// rangeRule is R-4s, which looks for a pair of points beyond
+// 2 sigma, on opposite sides.
+type rangeRule struct{}
+
+func (rangeRule) Name() string { return "R-4s" }
+func (rangeRule) Window() int  { return 2 }
+
+func init() {
+	for _, r := range []Rule{
+		inARow("1-2s", 1, 2, 2, SeverityWarn),
+		inARow("1-3s", 1, 3, 3, SeverityReject),
+		inARow("2-2s", 2, 2, 2, SeverityReject),
+		rangeRule{},
+		inARow("4-1s", 4, 1, 1, SeverityReject),
+		inARow("10x", 10, 0, 10, SeverityReject),
+	} {
+		mustRegister(r)
+	}
+	mustRegisterSet("Westgard", "1-2s", "1-3s", "2-2s", "R-4s", "4-1s", "10x")
+}
+
+// Evaluate detects the latest point and the one before it beyond 2 sigma
+// on opposite sides. It returns 4, or -4 if the latest point is below.
+func (rangeRule) Evaluate(history []Point) int {
+	window := states(history, 2, band(2))
+	switch {
+	case window[0] == StateAbove && window[1] == StateBelow:
+		return 4
+	case window[0] == StateBelow && window[1] == StateAbove:
+		return -4
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package WesternElectric
+
+import (
+	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
+	"testing"
+)
+
+func Test_westgard(t *testing.T) {
+	// each case is judged against average == 0, sd == 1, and as
+	// usual the last entry must be the one that reports
+	tests := []struct {
+		name     string
+		rule     string
+		data     []float64
+		expect   int
+		severity Severity
+	}{
+		{name: "1-2s warns", rule: "1-2s", data: []float64{2.5}, expect: 2, severity: SeverityWarn},
+		{name: "1-3s", rule: "1-3s", data: []float64{-3.5}, expect: -3},
+		{name: "2-2s", rule: "2-2s", data: []float64{0, 2.5, 2.1}, expect: 2},
+		{name: "2-2s, not in a row", rule: "2-2s", data: []float64{2.5, 0, 2.1}, expect: 0},
+		{name: "2-2s, opposite sides", rule: "2-2s", data: []float64{-2.5, 2.1}, expect: 0},
+		{name: "R-4s", rule: "R-4s", data: []float64{2.5, -2.1}, expect: -4},
+		{name: "R-4s, same side", rule: "R-4s", data: []float64{2.5, 2.1}, expect: 0},
+		{name: "4-1s", rule: "4-1s", data: []float64{1.5, 1.1, 1.2, 1.9}, expect: 1},
+		{name: "4-1s, one within", rule: "4-1s", data: []float64{1.5, 0.9, 1.2, 1.9}, expect: 0},
+		{name: "10x", rule: "10x", data: []float64{-1, -0.1, -0.2, -0.1, -2, -0.1, -0.1, -0.5, -0.1, -0.1}, expect: -10},
+		{name: "9x", rule: "10x", data: []float64{1, -0.1, -0.2, -0.1, -2, -0.1, -0.1, -0.5, -0.1, -0.1}, expect: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Signal
+
+			rules, err := RuleSet(tt.rule)
+			if err != nil {
+				t.Fatalf("RuleSet() returned %v", err)
+			}
+			d := NewDetector(movingAverage.Mock(1), 1, rules...)
+			for _, datum := range tt.data {
+				v := d.Judge(Point{Datum: datum, Average: 0, SD: 1})
+				got = Signal{}
+				if len(v.Signals) > 0 {
+					got = v.Signals[0]
+				}
+			}
+			if got.Indicator != tt.expect || got.Severity != tt.severity {
+				t.Errorf("%s = %d %s, expected %d %s", tt.rule, got.Indicator, got.Severity,
+					tt.expect, tt.severity)
+			}
+		})
+	}
+}
+
+// Test_warningsArentRejections checks a 1-2s warning doesn't count
+// as the last anomaly.
+func Test_warningsArentRejections(t *testing.T) {
+	rules, _ := RuleSet("Westgard")
+	d := NewDetector(movingAverage.Mock(1), 1, rules...)
+	v := d.Judge(Point{Datum: 2.5, Average: 0, SD: 1})
+	if len(v.Signals) != 1 || v.Last() != 0 {
+		t.Errorf("Judge() = %v, last %d, expected one warning and last 0", v.Signals, v.Last())
+	}
+}
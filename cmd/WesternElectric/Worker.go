@@ -1,220 +1,194 @@
 package WesternElectric
 
 import (
-	"encoding/csv"
 	"fmt"
-	movingAverage "github.com/davecb/WesternElectric/pkg/MovingAverage"
-
 	"io"
-	"log"
-	"math"
-	"os"
-	"strconv"
 	"strings"
 )
 
 // worker reads the input and applies the rules, comparing the data
-// to a moving average. For testing convenience, it returns the last anomaly.
-func Worker(fp *os.File, nSamples, reporting int) int {
-	var nr, lastErr int
-	var average float64
-	var sd float64
-
-	// set up csv reader to read fields out of a file
-	r := csv.NewReader(fp)
-	r.Comma = ' '
-	r.Comment = '#'
-	r.FieldsPerRecord = -1 // ignore differences
-	r.LazyQuotes = true    // allow bad quoting
-
-	// set up moving average
-	add := movingAverage.New(nSamples)
-
-	// read lines containing a datestamp or other initial field, and a value
-	header(reporting)
-	for nr = 0; ; nr++ {
-		record, err := r.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			// we had a csv-reading error, die.
-			log.Fatalf("error %q, in %q, line %d\n", err, record, nr)
-		}
-		if len(record) < 2 {
-			// skip it, but complain
-			log.Printf("Too few fields in line %d, %q. Ignored.\n", nr, record)
-			continue
-		}
-		//log.Printf("read %q\n", record)
-
-		date := record[0]
-		// parse the value field
-		datum, err := strconv.ParseFloat(record[1], 64)
-		if err != nil {
-			// we had a float-parsing error
-			log.Printf("Invalid float64 in line %d, %q. Ignored.\n", nr, strings.Join(record, "\t"))
-			continue
-		}
-
-		//log.Printf("at time %q, got %g, average = %g, sd = %g\n", record[0], datum, average, sd)
-		if nr > nSamples {
-			// see if we break any of the rules, but only once we have an average to use
-			rcThree := ThreeSigma(datum, average, sd)
-			if rcThree != 0 {
-				lastErr = rcThree
-			}
-
-			rcTwo := TwoSigma(datum, average, sd)
-			if rcTwo != 0 {
-				lastErr = rcTwo
-			}
-
-			rcOne := OneSigma(datum, average, sd)
-			if rcOne != 0 {
-				lastErr = rcOne
-			}
+// to a moving average. For testing convenience, its result includes
+// the last anomaly.
+func Worker(fp io.Reader, opts Options, reporting int) (Result, error) {
+	if len(opts.Rules) == 0 {
+		opts.Rules = DefaultRules()
+	}
+	charts, err := newCharts(opts) // just for their columns
+	if err != nil {
+		return Result{}, err
+	}
+	keyed := opts.KeyColumn != ""
+	header(reporting, keyed, charts)
 
-			report(reporting, date, datum, average, sd, rcThree, rcTwo, rcOne)
+	// report fixed limits, as comments, once we know them
+	if opts.Limits != nil {
+		fmt.Printf("#limits %s\n", *opts.Limits)
+	}
+	onLimits := opts.OnLimits
+	opts.OnLimits = func(l Limits) {
+		fmt.Printf("#limits %s\n", l)
+		if onLimits != nil {
+			onLimits(l)
 		}
-		average, sd = add(datum)
 	}
-	return lastErr
+	return scan(fp, opts, func(e Event) {
+		report(reporting, opts.TimeFormat, keyed, opts.Rules, charts, e)
+	})
 }
 
-// report tells us what happened, in short or long form.
-func report(reportingMode int, date string, datum float64, average float64, sd float64, rcThree, rcTwo, rcOne int) {
+// report tells us what happened, in short or long form, with the
+// datestamp in the time format, if there is one, followed by the key
+// of the series, if there are several.
+func report(reportingMode int, format string, keyed bool, rules []Rule, charts []Chart, e Event) {
 	// 	print stats and a visual indicator of broken rules
-	var three, two, one string
-
-	// hide zeroes
-	switch rcThree {
-	case -3:
-		three = " -3" // -3 sigma
-	case 3:
-		three = " 3"
-	default:
-		three = ""
+	date, datum, average, sd := e.When(format), e.Datum, e.Mean, e.SD
+	if keyed {
+		date += " " + e.Key
 	}
-	switch rcTwo {
-	case -2:
-		two = " -2"
-	case 2:
-		two = " 2"
-	default:
-		two = ""
+	if e.Missing > 0 {
+		// as a comment, so it doesn't spoil plots
+		fmt.Printf("#missing %d after %s\n", e.Missing, date)
+		return
 	}
-	switch rcOne {
-	case -1:
-		one = " -1"
-	case 3:
-		one = " 1"
-	default:
-		one = ""
+	flags := make([]string, len(rules), len(rules)+len(charts))
+
+	// one column per rule, hiding zeroes, with warnings marked "w"
+	for i, r := range rules {
+		if rc := e.Indicator(r.Name()); rc != 0 {
+			flags[i] = fmt.Sprintf(" %d", rc)
+			if severity(r) == SeverityWarn {
+				flags[i] += "w"
+			}
+		}
+	}
+	// and one per chart
+	for _, c := range charts {
+		var flag string
+		if rc := e.Indicator(c.Name()); rc != 0 {
+			flag = fmt.Sprintf(" %d", rc)
+		}
+		flags = append(flags, flag)
+	}
+	// then the charts' own values
+	var values string
+	for _, x := range e.Charts {
+		values += fmt.Sprintf(" %0.4f", x)
 	}
 
 	switch reportingMode {
 	case 0: // print a table of date, datum and the +/- sigma lines, then the indicators as digits
-		fmt.Printf("%s %0.4f %0.4f %0.4f %0.4f %0.4f %0.4f %0.4f %0.4f %s %s %s\n",
+		fmt.Printf("%s %0.4f %0.4f %0.4f %0.4f %0.4f %0.4f %0.4f %0.4f%s %s\n",
 			date, datum, average,
 			average+sd, average-sd,
 			average+2*sd, average-2*sd,
 			average+3*sd, average-3*sd,
-			three, two, one)
+			values, strings.Join(flags, " "))
 
 	case 1:
 		// just a report, for people to read
-		fmt.Printf("%s %f %0.4f %0.4f %s %s %s\n", date, datum, average, sd, three, two, one)
+		fmt.Printf("%s %f %0.4f %0.4f%s %s\n", date, datum, average, sd, values, strings.Join(flags, " "))
 	}
 }
 
-// header prints a header for the columns
-func header(mode int) {
+// header prints a header for the columns, including any for the key
+// and the charts
+func header(mode int, keyed bool, charts []Chart) {
+	var columns string
+
+	date := "#date"
+	if keyed {
+		date += " key"
+	}
+
+	for _, c := range charts {
+		columns += " " + strings.Join(c.Columns(), " ")
+	}
 	switch mode {
 	case 0: // print headers for a table, for plotting and/or spreadsheets
-		fmt.Printf("#date datum average average+sd average-sd average+2*sd average-2*sd average+3*sd average-3*sd flags\n")
+		fmt.Printf("%s datum average average+sd average-sd average+2*sd average-2*sd average+3*sd average-3*sd%s flags\n", date, columns)
 	case 1: // headers for just a report, aligned for people to scan
-		fmt.Printf("%s %s         %s     %s      %s\n", "#date", "datum", "average", "stddev", "flags")
+		fmt.Printf("%s %s         %s     %s%s      %s\n", date, "datum", "average", "stddev", columns, "flags")
 	}
 }
 
+// ThreeSigma, as a rule, looks only at the latest point.
+func (threeSigma) Evaluate(history []Point) int {
+	return ThreeSigma(history[0].Datum, history[0].Average, history[0].SD)
+}
+
 // ThreeSigma does the classic single-sample at 3 sigma test and returns an indicator
 // to identify anomalies, in this case, "spikes".
 func ThreeSigma(datum, average, sd float64) int {
-	if math.Abs(datum) > average+(3*sd) {
-		if datum > 0 {
-			return 3
-		} else {
-			return -3
-		}
+	switch {
+	case datum > average+(3*sd):
+		return 3
+	case datum < average-(3*sd):
+		return -3
 	}
 	return 0
 }
 
 // TwoSigma detects 2 out of 3 points at +/- 2 sigma, to detect
 // step-functions and "bands".
-func TwoSigma(datum, average, sd float64) int {
+func (twoSigma) Evaluate(history []Point) int {
 
-	// record its state
-	switch {
-	case datum > average+(2*sd):
-		threeSamples[0] = StateAbove
-	case datum < average-(2*sd):
-		threeSamples[0] = StateBelow
-	default:
-		threeSamples[0] = StateNA
-	}
-	// see if we have two out of three
-	if twoOf(threeSamples) {
-		threeSamples = shiftRight(threeSamples)
-		if datum > 0 {
+	// record their states
+	window := states(history, 3, band(2))
+	// see if we have two out of three, on the side of the latest
+	if twoOf(window) {
+		if window[0] == StateAbove {
 			return 2
 		} else {
 			return -2
 		}
 	}
-	// get ready for the next test
-	threeSamples = shiftRight(threeSamples)
 	return 0
 }
 
 // oneSigma detects  4/5 at 1 +/- sigma, again for
 // bands and step-functions.
-func OneSigma(datum, average, sd float64) int {
+func (oneSigma) Evaluate(history []Point) int {
 
-	// record its state
-	switch {
-	case datum > average+sd:
-		fiveSamples[0] = StateAbove
-	case datum < average-(2*sd):
-		fiveSamples[0] = StateBelow
-	default:
-		fiveSamples[0] = StateNA
-	}
-	if fourOf(fiveSamples) {
-		fiveSamples = shiftRight(fiveSamples)
-		if datum > 0 {
+	// record their states
+	window := states(history, 5, band(1))
+	if fourOf(window) {
+		if window[0] == StateAbove {
 			return 2
 		} else {
 			return -2
 		}
 	}
-	fiveSamples = shiftRight(fiveSamples)
 	return 0
 }
 
-// noSigma, if implemented, would detect  9/9 on the same side of 0
+// NoSigma detects 9/9 on the same side of the average, to detect
+// slow drifts that never get as far as 1 sigma.
+func (noSigma) Evaluate(history []Point) int {
+
+	// record their states
+	window := states(history, 9, func(p Point) State {
+		switch {
+		case p.Datum > p.Average:
+			return StateAbove
+		case p.Datum < p.Average:
+			return StateBelow
+		default:
+			return StateNA
+		}
+	})
+	if nOf(window, 9) {
+		if history[0].Datum > history[0].Average {
+			return 9
+		} else {
+			return -9
+		}
+	}
+	return 0
+}
 
 /*
  * infrastructure for the tests
  */
-var threeSamples, fiveSamples []State
-
-func init() {
-	// state vectors for twp of three, four of five
-	threeSamples = make([]State, 3)
-	fiveSamples = make([]State, 5)
-}
 
 // twoOf reports true if two states match
 func twoOf(threes []State) bool {
@@ -223,7 +197,7 @@ func twoOf(threes []State) bool {
 
 // fourOf reports true if four states match
 func fourOf(fives []State) bool {
-	return nOf(fives, 2)
+	return nOf(fives, 4)
 }
 
 // nOf reports true if N states match the first, counting the
@@ -248,6 +222,43 @@ func nOf(window []State, matches int) bool {
 	return false
 }
 
+// states classifies the points of a history, newest first, into
+// a window of n states, shifting each in as it would have arrived.
+// Points we haven't seen yet are left as NA.
+func states(history []Point, n int, classify func(p Point) State) []State {
+	window := make([]State, n)
+
+	if len(history) < n {
+		n = len(history)
+	}
+	for i := n - 1; i >= 0; i-- {
+		window = shiftRight(window)
+		window[0] = classify(history[i])
+	}
+	return window
+}
+
+// moves classifies the last n moves of a history, from each point to
+// the next, as up or down, newest first. Moves we haven't seen yet, and
+// points that didn't move, are left as NA.
+func moves(history []Point, n int) []State {
+	window := make([]State, n)
+
+	if len(history)-1 < n {
+		n = len(history) - 1
+	}
+	for i := n - 1; i >= 0; i-- {
+		window = shiftRight(window)
+		switch {
+		case history[i].Datum > history[i+1].Datum:
+			window[0] = StateUp
+		case history[i].Datum < history[i+1].Datum:
+			window[0] = StateDown
+		}
+	}
+	return window
+}
+
 // shiftRight moves everything to the right, zero-filling
 func shiftRight(window []State) []State {
 	for i := len(window) - 1; i > 0; i-- {
@@ -262,15 +273,21 @@ func shiftRight(window []State) []State {
 type State int32
 
 const (
-	StateNA    State = 0
-	StateAbove State = 1
-	StateBelow State = 2
+	StateNA     State = 0
+	StateAbove  State = 1
+	StateBelow  State = 2
+	StateUp     State = 3 // higher than the sample before it
+	StateDown   State = 4 // lower than the sample before it
+	StateWithin State = 5 // inside the +/- cutoff
 )
 
 var StateName = map[int32]string{
 	0: "NA",
 	1: "Above",
 	2: "Below",
+	3: "Up",
+	4: "Down",
+	5: "Within",
 }
 
 func (x State) String() string {
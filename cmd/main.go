@@ -6,6 +6,8 @@ import (
 	we "github.com/davecb/WesternElectric/cmd/WesternElectric"
 	"log"
 	"os"
+	"strings"
+	"time"
 )
 
 /*
@@ -20,13 +22,67 @@ func usage() {
 	os.Exit(1)
 }
 
+// isSet reports true if a flag was given on the command line
+func isSet(name string) bool {
+	var found bool
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
 func main() {
-	var nSamples, reportingMode int
+	var nSamples, minSamples, reportingMode, phaseI, period, subgroup int
 	var report, table bool
+	var malformed, ruleSet, baseline, limitsFile, saveLimits, average, season, spread, attribute string
+	var delimiter, dateColumns, valueColumn, keyColumn, sizeColumn, timeLayout, timeZone, timeFormat, gaps, aggregate string
+	var alpha, beta, gamma, lambda, L, cusumK, cusumH, sampleSize float64
+	var cusum, residuals, imr bool
+	var slot, interval, bucket time.Duration
 
+	flag.StringVar(&delimiter, "delimiter", "space", "what separates the fields: space, tab, comma or whitespace, for any run of spaces and tabs")
+	flag.StringVar(&dateColumns, "dateColumns", "1", "comma-separated columns of the datestamp, by number from 1 or by name from a \"#\" header line")
+	flag.StringVar(&valueColumn, "valueColumn", "2", "column of the value, by number from 1 or by name from a \"#\" header line")
+	flag.StringVar(&keyColumn, "keyColumn", "", "column of a key, such as a host, by number or name, to analyse each series in the input separately")
+	flag.StringVar(&timeLayout, "timeLayout", "", "Go time layout of the datestamps, such as \"2006-01-02 15:04:05\" (default recognize RFC 3339, Unix seconds or milliseconds, 15:04 and 01/02/06 03:04 PM)")
+	flag.StringVar(&timeZone, "timeZone", "UTC", "time zone of datestamps that don't have one, such as America/Toronto or Local")
+	flag.StringVar(&timeFormat, "timeFormat", "", "Go time layout to report the datestamps in, such as 2006-01-02T15:04:05Z07:00 (default as they were read)")
+	flag.DurationVar(&bucket, "bucket", 0, "group the data into buckets of this much time, such as 10m, and apply the rules to an aggregate of each")
+	flag.StringVar(&aggregate, "aggregate", "mean", "aggregate of each bucket: mean, sum, count, p50, p95, p99 or max")
+	flag.DurationVar(&interval, "interval", 0, "interval the data are expected at, such as 10m, to find gaps in them")
+	flag.StringVar(&gaps, "gaps", "missing", "what to do about gaps: report them as missing, reset the rules, carry the average forward or interpolate")
 	flag.IntVar(&nSamples, "nSamples", 5, "number of samples in the moving average")
+	flag.IntVar(&minSamples, "minSamples", 0, "number of samples in the moving average before applying the rules (default nSamples)")
+	flag.StringVar(&average, "average", "simple", "moving average to compare against: simple, sliding, median, ewma or holtwinters")
+	flag.Float64Var(&alpha, "alpha", 0.2, "smoothing factor for the ewma moving average, or the holtwinters level, > 0 and <= 1")
+	flag.Float64Var(&beta, "beta", 0.1, "smoothing factor for the holtwinters trend, > 0 and <= 1")
+	flag.Float64Var(&gamma, "gamma", 0.1, "smoothing factor for the holtwinters seasons, > 0 and <= 1")
+	flag.IntVar(&period, "period", 144, "samples in a holtwinters season, such as 144 ten-minute samples a day")
+	flag.BoolVar(&residuals, "residuals", false, "apply the rules to the differences from the average or forecast")
+	flag.StringVar(&season, "season", "", "compare to the same time of the day or week, instead of the samples just before")
+	flag.DurationVar(&slot, "slot", 10*time.Minute, "width of the time slots for --season, such as 10m")
+	flag.Float64Var(&lambda, "lambda", 0, "run an EWMA control chart alongside the rules, with this weight, > 0 and <= 1")
+	flag.Float64Var(&L, "L", 3, "width of the EWMA chart's limits, in sigmas")
+	flag.IntVar(&subgroup, "subgroup", 0, "group this many consecutive samples, or the first this many in each --bucket, into subgroups, from 2 to 25, and judge their means")
+	flag.StringVar(&spread, "spread", "range", "spread of the subgroups to use for their limits: range or sd")
+	flag.StringVar(&attribute, "attribute", "", "use an attribute chart for counts: p, np, c or u")
+	flag.StringVar(&sizeColumn, "sizeColumn", "", "column of the sample size for the p, np and u charts, by number or name (default the one after the value)")
+	flag.Float64Var(&sampleSize, "sampleSize", 0, "sample size for the p, np and u charts, for lines that don't have one")
+	flag.BoolVar(&imr, "imr", false, "use an I-MR chart: sigma from the moving range, and a moving-range chart alongside the rules")
+	flag.BoolVar(&cusum, "cusum", false, "run a CUSUM chart alongside the rules")
+	flag.Float64Var(&cusumK, "cusumK", 0.5, "the CUSUM's reference value, in sigmas")
+	flag.Float64Var(&cusumH, "cusumH", 5, "the CUSUM's decision interval, in sigmas")
 	flag.BoolVar(&report, "report", false, "report anomalies only")
 	flag.BoolVar(&table, "table", false, "report table of results & anomalies (default)")
+	flag.StringVar(&malformed, "malformed", "skip", "what to do with malformed lines: skip, count or abort")
+	flag.StringVar(&ruleSet, "rules", "WesternElectric", "comma-separated rules and rule sets to apply, in order, such as WesternElectric, Nelson or Westgard")
+	flag.StringVar(&baseline, "baseline", "", "compute fixed control limits from this file of known-good data")
+	flag.IntVar(&phaseI, "phase1", 0, "compute fixed control limits from the first N points")
+	flag.StringVar(&limitsFile, "limits", "", "apply fixed control limits saved in this file")
+	flag.StringVar(&saveLimits, "saveLimits", "", "save the limits from --baseline or --phase1 in this file")
 	flag.Parse()
 
 	switch {
@@ -38,6 +94,17 @@ func main() {
 	case report:
 		reportingMode = 1
 	}
+	switch {
+	case baseline != "" && phaseI != 0, baseline != "" && limitsFile != "", phaseI != 0 && limitsFile != "":
+		log.Printf("Only one of baseline, phase1 and limits may be specified. Halting\n")
+		usage()
+	case saveLimits != "" && baseline == "" && phaseI == 0:
+		log.Printf("saveLimits needs limits to save, from baseline or phase1. Halting\n")
+		usage()
+	case saveLimits != "" && keyColumn != "":
+		log.Printf("saveLimits saves the limits of a single series, not one per key. Halting\n")
+		usage()
+	}
 
 	if flag.NArg() < 1 {
 		fmt.Fprint(os.Stderr, "You must supply an input file, or '-' and a stream on stdin\n\n") //nolint
@@ -47,10 +114,90 @@ func main() {
 		fmt.Fprintf(os.Stderr, "You must specify a number of samples > 1 for the moving average, observed %d\n\n", nSamples) //nolint
 		usage()
 	}
+	if minSamples != 0 && minSamples < 2 {
+		fmt.Fprintf(os.Stderr, "You must specify a minimum number of samples > 1, observed %d\n\n", minSamples) //nolint
+		usage()
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err) //nolint
+		usage()
+	}
+	policy, err := we.ParsePolicy(malformed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err) //nolint
+		usage()
+	}
+	gapPolicy, err := we.ParseGapPolicy(gaps)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err) //nolint
+		usage()
+	}
 	log.SetFlags(log.Lshortfile | log.Ldate | log.Ltime) // show file:line in logs
 
+	var saved we.LimitsDocument
+	if limitsFile != "" {
+		saved, err = we.LoadLimits(limitsFile)
+		if err != nil {
+			log.Fatalf("%v, halting.\n", err)
+		}
+		if !isSet("rules") {
+			// use the rules the limits were saved with
+			ruleSet = strings.Join(saved.Rules, ",")
+		}
+		if !isSet("subgroup") && saved.Subgroup != 0 {
+			// and the subgroups, which they only make sense with
+			subgroup, spread = saved.Subgroup, saved.Spread
+		}
+	}
+	rules, err := we.RuleSet(ruleSet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err) //nolint
+		usage()
+	}
+
 	filename := flag.Arg(0)
 
-	rc := we.ApplyRules(filename, nSamples, reportingMode)
-	os.Exit(rc)
+	opts := we.Options{NSamples: nSamples, MinSamples: minSamples, Malformed: policy, Rules: rules, PhaseI: phaseI,
+		Average: average, Alpha: alpha, Beta: beta, Gamma: gamma, Period: period, Residuals: residuals, IMR: imr,
+		Subgroup: subgroup, Spread: spread, Attribute: attribute, SampleSize: sampleSize,
+		Season: season, Slot: slot, Lambda: lambda, L: L,
+		CUSUM: cusum, K: cusumK, H: cusumH,
+		Delimiter: delimiter, DateColumns: strings.Split(dateColumns, ","), ValueColumn: valueColumn, KeyColumn: keyColumn, SizeColumn: sizeColumn,
+		TimeLayout: timeLayout, Location: location, TimeFormat: timeFormat,
+		Bucket: bucket, Aggregate: aggregate, Interval: interval, Gaps: gapPolicy}
+	source := filename
+	switch {
+	case baseline != "":
+		limits, err := we.BaselineLimits(baseline, opts)
+		if err != nil {
+			log.Fatalf("%v, halting.\n", err)
+		}
+		opts.Limits = &limits
+		source = baseline
+	case limitsFile != "":
+		opts.Limits = &saved.Limits
+	}
+
+	res, err := we.ApplyRules(filename, opts, reportingMode)
+	if err != nil {
+		log.Fatalf("%v, halting.\n", err)
+	}
+	if saveLimits != "" {
+		err = we.SaveLimits(saveLimits, we.NewLimitsDocument(*res.Limits, opts, source))
+		if err != nil {
+			log.Fatalf("%v, halting.\n", err)
+		}
+	}
+	if res.Skipped > 0 {
+		log.Printf("%d of %d lines were malformed and skipped\n", res.Skipped, res.Read)
+	}
+	if res.Gaps > 0 {
+		log.Printf("%d gaps, with %d points missing\n", res.Gaps, res.Missing)
+	}
+	if len(res.Unjudged) > 0 {
+		log.Printf("%d series had too few points for phase I, and weren't judged: %s\n",
+			len(res.Unjudged), strings.Join(res.Unjudged, ", "))
+	}
+	os.Exit(res.Last)
 }
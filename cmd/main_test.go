@@ -117,11 +117,13 @@ func Test_twoSigma(t *testing.T) {
 			} else {
 				add = movingAverage.New(tt.nSamples)
 			}
+			rules, _ := WesternElectric.RuleSet("TwoSigma")
+			d := WesternElectric.NewDetector(add, tt.nSamples, rules...)
 			t.Logf("n:  flag  data av   sd   a+3sd\n")
 			for i, datum = range tt.data {
 				if i > tt.nSamples {
 					// once we have an average, start looking
-					got = WesternElectric.TwoSigma(datum, average, sd)
+					got = d.Judge(WesternElectric.Point{Datum: datum, Average: average, SD: sd}).Last()
 					t.Logf("%-0.2d: %-5.5q %-4.2g %-4.2g %-4.2g %-4.2g\n", i, got, datum, oldAv, oldSD, oldAv+3*oldSD)
 					oldAv, oldSD = average, sd
 				}
@@ -148,13 +150,16 @@ func Test_westernElectric(t *testing.T) {
 			name:     "example.csv", // FIXME, break up into sets
 			file:     "./testdata/example.csv",
 			nSamples: 5,
-			expect:   2,
+			expect:   -2, // the last point is far below the average
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rc := WesternElectric.ApplyRules(tt.file, tt.nSamples, 0)
-			if rc != tt.expect {
+			res, err := WesternElectric.ApplyRules(tt.file, WesternElectric.Options{NSamples: tt.nSamples}, 0)
+			if err != nil {
+				t.Fatalf("ApplyRules() returned %v", err)
+			}
+			if res.Last != tt.expect {
 				t.Errorf("we found a failure\n")
 			}
 
@@ -0,0 +1,90 @@
+package movingAverage
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+ * Attribute charts -- for counts, which are binomial or Poisson rather than
+ * normal, so their sigma follows from their mean, and from the sample size
+ * of each point, giving limits that vary from point to point. With d
+ * defectives or c defects in a sample of n, and the totals over the window,
+
+	p chart:  p = d/n     centre pbar = sum(d)/sum(n)   sigma = sqrt(pbar*(1-pbar)/n)
+	np chart: np = d      centre n*pbar                 sigma = sqrt(n*pbar*(1-pbar))
+	c chart:  c           centre cbar = mean(c)         sigma = sqrt(cbar)
+	u chart:  u = c/n     centre ubar = sum(c)/sum(n)   sigma = sqrt(ubar/n)
+
+ * See Montgomery, "Introduction to Statistical Quality Control", chapter 7.
+*
+*/
+
+// AttributeWindow keeps the counts and sample sizes of the last nSamples
+// points, for a p, np, c or u chart.
+type AttributeWindow struct {
+	window           // of the counts
+	Kind   string    // "p", "np", "c" or "u"
+	sizes  []float64 // and their sample sizes, in step
+	counts float64   // the totals over the window
+	total  float64
+}
+
+// NewAttributeWindow creates an attribute chart of a kind, with its
+// centre line from the last nSamples points.
+func NewAttributeWindow(kind string, nSamples int) (*AttributeWindow, error) {
+	switch kind {
+	case "p", "np", "c", "u":
+	default:
+		return nil, fmt.Errorf("unknown attribute chart %q, expected p, np, c or u", kind)
+	}
+	return &AttributeWindow{
+		window: window{bins: make([]float64, nSamples)},
+		Kind:   kind,
+		sizes:  make([]float64, nSamples),
+	}, nil
+}
+
+// Add adds a count, of defectives or defects, in a sample of size,
+// which a c chart ignores.
+func (w *AttributeWindow) Add(count, size float64) {
+	if w.Kind == "c" {
+		size = 1
+	}
+	i := w.i
+	old, full := w.push(count)
+	if full {
+		w.counts -= old
+		w.total -= w.sizes[i]
+	}
+	w.sizes[i] = size
+	w.counts += count
+	w.total += size
+}
+
+// Value returns what the chart plots for a count in a sample of size:
+// the proportion, the count, or the count per unit.
+func (w *AttributeWindow) Value(count, size float64) float64 {
+	switch w.Kind {
+	case "p", "u":
+		return count / size
+	}
+	return count
+}
+
+// Limits returns the centre line, and sigma, for a sample of size.
+func (w *AttributeWindow) Limits(size float64) (float64, float64) {
+	if w.total == 0 {
+		return 0, 0
+	}
+	bar := w.counts / w.total // pbar, cbar or ubar
+	switch w.Kind {
+	case "p":
+		return bar, math.Sqrt(bar * (1 - bar) / size)
+	case "np":
+		return size * bar, math.Sqrt(size * bar * (1 - bar))
+	case "u":
+		return bar, math.Sqrt(bar / size)
+	}
+	return bar, math.Sqrt(bar)
+}
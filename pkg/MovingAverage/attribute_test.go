@@ -0,0 +1,48 @@
+package movingAverage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestAttribute(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		kind   string
+		counts [][2]float64 // count and size
+		size   float64      // of the point to get limits for
+		value  float64      // of the last count
+		centre float64
+		sigma  float64
+	}{
+		{kind: "p", counts: [][2]float64{{5, 100}, {10, 100}}, size: 100,
+			value: 0.1, centre: 0.075, sigma: math.Sqrt(0.075 * 0.925 / 100)},
+		{kind: "p", counts: [][2]float64{{5, 100}, {10, 100}}, size: 25,
+			value: 0.1, centre: 0.075, sigma: math.Sqrt(0.075 * 0.925 / 25)},
+		{kind: "p", counts: [][2]float64{{5, 100}, {10, 100}, {0, 200}}, size: 100, // the first drops out
+			value: 0, centre: 10.0 / 300, sigma: math.Sqrt(10.0 / 300 * 290 / 300 / 100)},
+		{kind: "np", counts: [][2]float64{{5, 100}, {10, 100}}, size: 100,
+			value: 10, centre: 7.5, sigma: math.Sqrt(100 * 0.075 * 0.925)},
+		{kind: "c", counts: [][2]float64{{4, 0}, {6, 0}}, size: 0,
+			value: 6, centre: 5, sigma: math.Sqrt(5)},
+		{kind: "u", counts: [][2]float64{{3, 2}, {9, 4}}, size: 2,
+			value: 2.25, centre: 2, sigma: 1},
+	}
+	for _, tt := range tests {
+		w, err := NewAttributeWindow(tt.kind, 2)
+		assert.Equal(nil, err)
+		for _, c := range tt.counts {
+			w.Add(c[0], c[1])
+		}
+		last := tt.counts[len(tt.counts)-1]
+		assert.InDelta(tt.value, w.Value(last[0], last[1]), 1e-12, tt.kind)
+		centre, sigma := w.Limits(tt.size)
+		assert.InDelta(tt.centre, centre, 1e-12, tt.kind)
+		assert.InDelta(tt.sigma, sigma, 1e-12, tt.kind)
+	}
+
+	_, err := NewAttributeWindow("x", 2)
+	assert.True(err != nil)
+}
@@ -0,0 +1,46 @@
+package movingAverage
+
+import (
+	"math"
+)
+
+/*
+ * Exponentially-weighted moving average -- each new sample moves the mean a
+ * fraction alpha of the way towards it, so old samples fade away rather than
+ * dropping out of a window, and no buffer is needed. The variance is weighted
+ * the same way, using the incremental form from Tony Finch's
+ * "Incremental calculation of weighted mean and variance", 2009
+ * https://fanf2.user.srcf.net/hermes/doc/antiforgery/stats.pdf
+
+	diff := x - mean
+	incr := alpha * diff
+	mean := mean + incr
+	variance := (1 - alpha) * (variance + diff * incr)
+
+*
+*/
+
+// NewEWMA takes a smoothing factor, alpha, between 0 and 1, and generates
+// an "add" function that computes an exponentially-weighted moving
+// average and standard deviation as each sample is added. Larger values
+// of alpha react faster, smaller ones are smoother.
+func NewEWMA(alpha float64) func(s float64) (float64, float64) {
+	var Mean, Variance float64
+	var started bool
+
+	return func(new float64) (float64, float64) {
+		if !started {
+			// start from the first sample, rather than from zero
+			Mean = new
+			started = true
+			return Mean, 0
+		}
+		diff := new - Mean
+		incr := alpha * diff
+		Mean = Mean + incr
+		Variance = (1 - alpha) * (Variance + diff*incr)
+
+		// return the mean and SD
+		return Mean, math.Sqrt(Variance)
+	}
+}
@@ -0,0 +1,44 @@
+package movingAverage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEWMA(t *testing.T) {
+	assert := assert.New(t)
+
+	add := NewEWMA(0.5)
+	a, b := add(1)
+	t.Logf("1                       = %g", a)
+	assert.Equal(1.0, a)
+	assert.Equal(0.0, b)
+
+	a, b = add(3)
+	t.Logf("1 + 0.5 * (3-1)         = %g", a)
+	assert.Equal(2.0, a)
+	assert.Equal(1.0, b) // 0.5 * (0 + 2*1)
+
+	a, b = add(2)
+	t.Logf("2 + 0.5 * (2-2)         = %g", a)
+	assert.Equal(2.0, a)
+	assert.InDelta(0.7071067811865476, b, 1e-12) // sqrt(0.5 * (1 + 0))
+
+	a, _ = add(6)
+	t.Logf("2 + 0.5 * (6-2)         = %g", a)
+	assert.Equal(4.0, a)
+}
+
+func TestEWMAConverges(t *testing.T) {
+	assert := assert.New(t)
+
+	// a constant input settles at itself, with no deviation
+	add := NewEWMA(0.1)
+	var a, b float64
+	add(100)
+	for i := 0; i < 500; i++ {
+		a, b = add(5)
+	}
+	assert.InDelta(5.0, a, 1e-9)
+	assert.InDelta(0.0, b, 1e-3)
+}
@@ -0,0 +1,97 @@
+package movingAverage
+
+import (
+	"math"
+)
+
+/*
+ * Holt-Winters -- triple exponential smoothing, which forecasts each sample
+ * from a level, a trend and a seasonal adjustment, each smoothed in the
+ * additive form from Hyndman and Athanasopoulos, "Forecasting: Principles
+ * and Practice", 2nd ed., section 7.5. For a season of m samples,
+
+	forecast := level + trend + season[t%m]
+	oldLevel := level
+	level := alpha*(x - season[t%m]) + (1-alpha)*(level+trend)
+	trend := beta*(level-oldLevel) + (1-beta)*trend
+	season[t%m] := gamma*(x - level) + (1-gamma)*season[t%m]
+
+ * The first m samples set the starting level, their mean, and the seasonal
+ * adjustments, their differences from it, with no trend. The sd is of the
+ * errors in the forecasts, x - forecast, smoothed the same way as the level.
+*
+*/
+
+// HoltWinters forecasts the next sample from the level, trend and
+// season of the ones before it.
+type HoltWinters struct {
+	Alpha  float64 // smoothing for the level, and the sd of the errors
+	Beta   float64 // smoothing for the trend
+	Gamma  float64 // smoothing for the seasons
+	Period int     // samples in a season, such as 144 ten-minute samples a day
+
+	level    float64
+	trend    float64
+	season   []float64
+	variance float64 // of the errors
+	n        int     // samples seen
+}
+
+// NewHoltWinters creates a forecaster for a season of period samples,
+// with smoothing factors between 0 and 1.
+func NewHoltWinters(alpha, beta, gamma float64, period int) *HoltWinters {
+	return &HoltWinters{
+		Alpha:  alpha,
+		Beta:   beta,
+		Gamma:  gamma,
+		Period: period,
+		season: make([]float64, period),
+	}
+}
+
+// Ready reports true once a full season has been seen, so the
+// forecasts have something to go on.
+func (h *HoltWinters) Ready() bool {
+	return h.n >= h.Period
+}
+
+// Forecast returns the forecast for the next sample.
+func (h *HoltWinters) Forecast() float64 {
+	return h.level + h.trend + h.season[h.n%h.Period]
+}
+
+// Add learns from a new sample, and returns the forecast for the next one
+// and the sd of the forecast errors. Until it has seen a season, it returns
+// the mean and sd of what it has, like New.
+func (h *HoltWinters) Add(new float64) (float64, float64) {
+	if !h.Ready() {
+		h.season[h.n] = new
+		h.n++
+		Mean, S := walk(h.season[:h.n])
+		if h.Ready() {
+			// start the level at the mean, and the seasons around it
+			h.level = Mean
+			for i := range h.season {
+				h.season[i] -= Mean
+			}
+			return h.Forecast(), 0
+		}
+		return Mean, sd(S, h.n)
+	}
+
+	i := h.n % h.Period
+	e := new - h.Forecast()
+	if h.n == h.Period {
+		h.variance = e * e
+	} else {
+		h.variance = (1-h.Alpha)*h.variance + h.Alpha*e*e
+	}
+	oldLevel := h.level
+	h.level = h.Alpha*(new-h.season[i]) + (1-h.Alpha)*(h.level+h.trend)
+	h.trend = h.Beta*(h.level-oldLevel) + (1-h.Beta)*h.trend
+	h.season[i] = h.Gamma*(new-h.level) + (1-h.Gamma)*h.season[i]
+	h.n++
+
+	// return the forecast and SD
+	return h.Forecast(), math.Sqrt(h.variance)
+}
@@ -0,0 +1,48 @@
+package movingAverage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestHoltWinters(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NewHoltWinters(0.5, 0.5, 0.5, 4)
+	for _, x := range []float64{1, 3, 5, 3} {
+		assert.False(h.Ready())
+		h.Add(x)
+	}
+	// level 3, seasons -2, 0, 2, 0
+	assert.True(h.Ready())
+	assert.Equal(1.0, h.Forecast())
+
+	a, b := h.Add(1) // exactly as forecast
+	t.Logf("forecast %g, sd %g", a, b)
+	assert.Equal(3.0, a)
+	assert.Equal(0.0, b)
+}
+
+// TestHoltWintersTrend shows a growing, cyclic series is forecast
+// closely, where a moving average lags behind it.
+func TestHoltWintersTrend(t *testing.T) {
+	assert := assert.New(t)
+
+	const period = 24
+	series := func(i int) float64 {
+		return 100 + float64(i) + 20*math.Sin(2*math.Pi*float64(i)/period)
+	}
+	h := NewHoltWinters(0.3, 0.1, 0.3, period)
+	add := New(period)
+	var forecast, mean, sdHW, sdMA float64
+	for i := 0; i < 50*period; i++ {
+		forecast, sdHW = h.Add(series(i))
+		mean, sdMA = add(series(i))
+	}
+	next := series(50 * period)
+	t.Logf("next %g: forecast %g, sd %g; mean %g, sd %g", next, forecast, sdHW, mean, sdMA)
+	assert.InDelta(next, forecast, 1)
+	assert.True(math.Abs(next-mean) > 10)
+	assert.True(sdHW < 1 && sdMA > 10)
+}
@@ -31,29 +31,97 @@ import (
 // New takes a number of sample to consider and generates a
 // function, conventionally called "add", that computes a moving average
 // and standard deviation as each sample is added to the sample set.
+// Until nSamples have been added, it averages just the ones it has.
 func New(nSamples int) func(s float64) (float64, float64) {
-	var i int
+	return NewSimpleWindow(nSamples).Add
+}
+
+// window holds the last nSamples samples, and knows how many
+// of its bins hold real samples rather than zeroes.
+type window struct {
+	bins []float64
+	i    int // the next bin to fill
+	n    int // the number of real samples
+}
+
+// push places a new value into a bin, returning the one it
+// replaced, if the window was already full.
+func (w *window) push(new float64) (float64, bool) {
+	old, full := w.bins[w.i], w.Ready()
+
+	w.bins[w.i] = new
+	w.i = (w.i + 1) % len(w.bins)
+	if !full {
+		w.n++
+	}
+	return old, full
+}
+
+// Ready reports true once the window holds nSamples real samples,
+// so the moving average is over its full width.
+func (w *window) Ready() bool {
+	return w.n == len(w.bins)
+}
+
+// Count returns the number of real samples in the window.
+func (w *window) Count() int {
+	return w.n
+}
+
+// SimpleWindow is the fixed-width window New uses: the last nSamples
+// samples, walked afresh on every Add. While it fills, its Count is
+// less than nSamples, and it averages just those.
+type SimpleWindow struct {
+	window
+}
+
+// NewSimpleWindow creates a moving average of nSamples.
+func NewSimpleWindow(nSamples int) *SimpleWindow {
+	return &SimpleWindow{window{bins: make([]float64, nSamples)}}
+}
+
+// Add places a new value into a bin, then iterates across the real
+// samples, getting a mean and a standard deviation.
+func (w *SimpleWindow) Add(new float64) (float64, float64) {
+	w.push(new)
+	Mean, S := walk(w.bins[:w.n])
+
+	// return the mean and SD
+	return Mean, sd(S, w.n)
+}
+
+// sd turns the accumulator for n samples into a standard deviation,
+// which is zero if we have only one.
+func sd(S float64, n int) float64 {
+	if n < 2 {
+		return 0
+	}
+	return math.Sqrt(S / float64(n-1))
+}
+
+// Cumulative generates an "add" function that computes the mean
+// and standard deviation of every sample it has been given, using
+// Knuth-Welford over the whole sequence rather than a window.
+// Used to compute fixed control limits from a baseline.
+func Cumulative() func(s float64) (float64, float64) {
+	var Mean, S float64
+	var k int
+
+	return func(new float64) (float64, float64) {
+		k++
+		oldMean := Mean
+		Mean = Mean + (new-Mean)/float64(k)
+		S = S + (new-Mean)*(new-oldMean)
+		return Mean, sd(S, k)
+	}
+}
 
-	bins := make([]float64, nSamples)
+// Fixed generates an "add" function that ignores its samples, and
+// always returns the same mean and standard deviation. Used for
+// fixed control limits.
+func Fixed(mean, sd float64) func(s float64) (float64, float64) {
 	return func(new float64) (float64, float64) {
-		var Mean, S float64 // S is the accumulator for the variance and SD
-		var k int
-
-		// First, place the new value into a bin
-		bins[i] = new
-		i = (i + 1) % nSamples
-
-		// Then iterate across the bins, getting a mean and a variance
-		for k = 0; k < nSamples; k++ {
-			x := bins[k]
-			oldMean := Mean
-			Mean = Mean + (x-Mean)/float64(k+1)
-			S = S + (x-Mean)*(x-oldMean)
-			//log.Printf("x = %g, mean = %g , k+1 = %d\n", x, Mean, k+1)
-		}
-
-		// return the mean and SD
-		return Mean, math.Sqrt(S / float64(nSamples-1))
+		return mean, sd
 	}
 }
 
@@ -11,24 +11,24 @@ func TestMovingAverage(t *testing.T) {
 
 	add := New(5)
 	a, b := add(1)
-	t.Logf("(1                  ) / 5 = %g", a)
-	assert.Equal(0.2, a)
-	assert.Equal(0.447213595499958, b)
+	t.Logf("(1                  ) / 1 = %g", a)
+	assert.Equal(1.0, a)
+	assert.Equal(0.0, b)
 
 	a, b = add(2)
-	t.Logf("(1+2                ) / 5 = %g", a)
-	assert.Equal(0.6, a)
-	assert.Equal(0.8944271909999159, b)
+	t.Logf("(1+2                ) / 2 = %g", a)
+	assert.Equal(1.5, a)
+	assert.Equal(0.7071067811865476, b)
 
 	a, b = add(3)
-	t.Logf("(1+2+3              ) / 5 = %g", a)
-	assert.Equal(1.2, a)
-	assert.Equal(1.3038404810405297, b)
+	t.Logf("(1+2+3              ) / 3 = %g", a)
+	assert.Equal(2.0, a)
+	assert.Equal(1.0, b)
 
 	a, b = add(4)
-	t.Logf("(1+2+3+4            ) / 5 = %g", a)
-	assert.Equal(2.0, a)
-	assert.Equal(1.5811388300841898, b)
+	t.Logf("(1+2+3+4            ) / 4 = %g", a)
+	assert.Equal(2.5, a)
+	assert.Equal(1.2909944487358056, b)
 
 	a, b = add(5)
 	t.Logf("(1+2+3+4+5          ) / 5 = %g", a)
@@ -62,17 +62,68 @@ func TestMovingAverage(t *testing.T) {
 
 }
 
+func TestWindowReady(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name   string
+		window interface {
+			Add(float64) (float64, float64)
+			Ready() bool
+			Count() int
+		}
+	}{
+		{name: "simple", window: NewSimpleWindow(3)},
+		{name: "sliding", window: NewSlidingWindow(3)},
+	}
+	for _, tt := range tests {
+		// ready once the third sample is in, and stays that way
+		for i, x := range []float64{1, 2, 3, 4} {
+			tt.window.Add(x)
+			assert.Equal(i >= 2, tt.window.Ready(), tt.name)
+		}
+		assert.Equal(3, tt.window.Count(), tt.name)
+	}
+}
+
+func TestCumulative(t *testing.T) {
+	assert := assert.New(t)
+
+	add := Cumulative()
+	a, b := add(1)
+	assert.Equal(1.0, a)
+	assert.Equal(0.0, b)
+
+	for _, x := range []float64{2, 3, 4, 5, 9, 3, 0, -9} {
+		a, b = add(x)
+	}
+	// all nine samples, unlike the moving average
+	assert.InDelta(2.0, a, 1e-12)
+	assert.InDelta(4.873397172404482, b, 1e-12)
+}
+
+func TestFixed(t *testing.T) {
+	assert := assert.New(t)
+
+	add := Fixed(10, 2)
+	for _, x := range []float64{1, 99, -5} {
+		a, b := add(x)
+		assert.Equal(10.0, a)
+		assert.Equal(2.0, b)
+	}
+}
+
 func ExampleMovingAverage() {
 
 	add := New(5)
 	a, _ := add(1)
-	fmt.Println("(1                  ) / 5 =", a)
+	fmt.Println("(1                  ) / 1 =", a)
 	a, _ = add(2)
-	fmt.Println("(1+2                ) / 5 =", a)
+	fmt.Println("(1+2                ) / 2 =", a)
 	a, _ = add(3)
-	fmt.Println("(1+2+3              ) / 5 =", a)
+	fmt.Println("(1+2+3              ) / 3 =", a)
 	a, _ = add(4)
-	fmt.Println("(1+2+3+4            ) / 5 =", a)
+	fmt.Println("(1+2+3+4            ) / 4 =", a)
 	a, _ = add(5)
 	fmt.Println("(1+2+3+4+5          ) / 5 =", a)
 	a, _ = add(9)
@@ -86,10 +137,10 @@ func ExampleMovingAverage() {
 	a, _ = add(-8)
 	fmt.Println("(          9+3+0-9-8) / 5 =", a)
 	// Output:
-	//(1                  ) / 5 = 0.2
-	//(1+2                ) / 5 = 0.6
-	//(1+2+3              ) / 5 = 1.2
-	//(1+2+3+4            ) / 5 = 2
+	//(1                  ) / 1 = 1
+	//(1+2                ) / 2 = 1.5
+	//(1+2+3              ) / 3 = 2
+	//(1+2+3+4            ) / 4 = 2.5
 	//(1+2+3+4+5          ) / 5 = 3
 	//(  2+3+4+5+9        ) / 5 = 4.6
 	//(    3+4+5+9+3      ) / 5 = 4.8
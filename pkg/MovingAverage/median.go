@@ -0,0 +1,81 @@
+package movingAverage
+
+import (
+	"sort"
+)
+
+/*
+ * Median and MAD -- a robust moving average, which reports the median of the
+ * window in place of the mean, and the median absolute deviation from it,
+ * scaled to be comparable to a standard deviation, in place of the sd.
+
+	MAD := median(|x - median(x)|)
+	sd := 1.4826 * MAD
+
+ * A single huge outlier moves the mean, and inflates the sd by its square,
+ * so the points after it can never look anomalous. It moves the median by
+ * at most one place, and the MAD likewise, so the rules keep working until
+ * half the window is contaminated. The price is a sort of the window on
+ * every call, O(nSamples log nSamples).
+*
+*/
+
+// madScale makes the MAD of normally-distributed data an estimate
+// of its standard deviation: it is 1/Φ⁻¹(3/4).
+const madScale = 1.482602218505602
+
+// NewMedian takes a number of samples to consider and generates an
+// "add" function like New's, which returns the rolling median and
+// scaled MAD instead of the mean and standard deviation.
+func NewMedian(nSamples int) func(s float64) (float64, float64) {
+	return NewMedianWindow(nSamples).Add
+}
+
+// MedianWindow holds the last nSamples for NewMedian, and sorts a copy
+// of them on each Add. Its Count is how many the median is of; while
+// that's even, the median is the mean of the middle two.
+type MedianWindow struct {
+	window
+	sorted []float64 // scratch space for the sorts
+}
+
+// NewMedianWindow creates a rolling median of nSamples.
+func NewMedianWindow(nSamples int) *MedianWindow {
+	return &MedianWindow{
+		window: window{bins: make([]float64, nSamples)},
+		sorted: make([]float64, nSamples),
+	}
+}
+
+// Add places a new value into a bin, then finds the median of the real
+// samples, and the median of their distances from it.
+func (w *MedianWindow) Add(new float64) (float64, float64) {
+	w.push(new)
+	sorted := w.sorted[:w.n]
+
+	copy(sorted, w.bins[:w.n])
+	Median := median(sorted)
+	for i, x := range w.bins[:w.n] {
+		if x < Median {
+			sorted[i] = Median - x
+		} else {
+			sorted[i] = x - Median
+		}
+	}
+	MAD := median(sorted)
+
+	// return the median and the scaled MAD
+	return Median, madScale * MAD
+}
+
+// median sorts the samples in place, and returns the middle one,
+// or the mean of the middle two.
+func median(samples []float64) float64 {
+	n := len(samples)
+
+	sort.Float64s(samples)
+	if n%2 == 1 {
+		return samples[n/2]
+	}
+	return (samples[n/2-1] + samples[n/2]) / 2
+}
@@ -0,0 +1,55 @@
+package movingAverage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMedian(t *testing.T) {
+	assert := assert.New(t)
+
+	add := NewMedian(5)
+	a, b := add(1)
+	t.Logf("median(1)               = %g", a)
+	assert.Equal(1.0, a)
+	assert.Equal(0.0, b)
+
+	a, b = add(2)
+	t.Logf("median(1,2)             = %g", a)
+	assert.Equal(1.5, a)
+	assert.InDelta(madScale*0.5, b, 1e-12) // |-0.5|, |0.5|
+
+	for _, x := range []float64{3, 4, 5} {
+		a, b = add(x)
+	}
+	t.Logf("median(1,2,3,4,5)       = %g", a)
+	assert.Equal(3.0, a)
+	assert.InDelta(madScale*1, b, 1e-12) // 2,1,0,1,2
+
+	a, b = add(9)
+	t.Logf("median(  2,3,4,5,9)     = %g", a)
+	assert.Equal(4.0, a)
+	assert.InDelta(madScale*1, b, 1e-12) // 2,1,0,1,5
+}
+
+// TestMedianOutlier shows a huge outlier barely moves the median and
+// MAD, where it swamps the mean and sd.
+func TestMedianOutlier(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []float64{10, 11, 9, 10, 11, 9, 10, 1000}
+	robust, simple := NewMedian(8), New(8)
+	var m, mad, mean, sd float64
+	for _, x := range data {
+		m, mad = robust(x)
+		mean, sd = simple(x)
+	}
+	t.Logf("median %g, MAD %g; mean %g, sd %g", m, mad, mean, sd)
+	assert.Equal(10.0, m)
+	assert.InDelta(madScale*1, mad, 1e-12)
+	assert.True(mean > 100 && sd > 300)
+
+	// so the next point that's out of line still stands out
+	assert.True(20 > m+3*mad)
+	assert.False(20 > mean+3*sd)
+}
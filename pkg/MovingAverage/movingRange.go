@@ -0,0 +1,94 @@
+package movingAverage
+
+import (
+	"math"
+)
+
+/*
+ * Moving range -- the individuals chart's estimate of sigma, for data with
+ * one value per sample. Rather than the standard deviation of the values,
+ * which a shift in the middle of the window inflates, it uses the average
+ * of the ranges between successive values, which a shift only touches once.
+
+	MR := |x[i] - x[i-1]|
+	sigma := mean(MR) / d2
+
+ * where d2 is the expected range of two normal samples, in sigmas. See
+ * Montgomery, "Introduction to Statistical Quality Control", section 6.4.
+*
+*/
+
+const (
+	D2 = 1.128 // d2, for the range of two samples
+	D4 = 3.267 // D4, the moving-range chart's upper limit is D4 * mean(MR)
+)
+
+// NewMovingRange takes a number of samples to consider and generates an
+// "add" function like New's, which returns the mean and the moving-range
+// estimate of sigma.
+func NewMovingRange(nSamples int) func(s float64) (float64, float64) {
+	return NewMovingRangeWindow(nSamples).Add
+}
+
+// MovingRangeWindow holds the last nSamples individual values for
+// NewMovingRange. A Count of values has one range fewer between them,
+// so its sigma is zero until it has two.
+type MovingRangeWindow struct {
+	window
+}
+
+// NewMovingRangeWindow creates a moving range of nSamples.
+func NewMovingRangeWindow(nSamples int) *MovingRangeWindow {
+	return &MovingRangeWindow{window{bins: make([]float64, nSamples)}}
+}
+
+// Add places a new value into a bin, then walks the real samples from
+// oldest to newest, getting the mean and the mean of the ranges.
+func (w *MovingRangeWindow) Add(new float64) (float64, float64) {
+	var sum, ranges float64
+
+	w.push(new)
+	oldest := 0
+	if w.Ready() {
+		oldest = w.i
+	}
+	prev := w.bins[oldest]
+	for k := 0; k < w.n; k++ {
+		x := w.bins[(oldest+k)%len(w.bins)]
+		sum += x
+		ranges += math.Abs(x - prev)
+		prev = x
+	}
+
+	// return the mean and the sigma
+	return sum / float64(w.n), sigma(ranges, w.n)
+}
+
+// CumulativeMovingRange generates an "add" function like Cumulative's,
+// which returns the mean and the moving-range estimate of sigma of all
+// the samples so far, for computing fixed limits.
+func CumulativeMovingRange() func(s float64) (float64, float64) {
+	var Mean, ranges, prev float64
+	var k int
+
+	return func(new float64) (float64, float64) {
+		k++
+		if k > 1 {
+			ranges += math.Abs(new - prev)
+		}
+		prev = new
+		Mean = Mean + (new-Mean)/float64(k)
+
+		// return the mean and the sigma
+		return Mean, sigma(ranges, k)
+	}
+}
+
+// sigma turns the sum of the ranges between n samples into an
+// estimate of sigma, which is zero if we have only one.
+func sigma(ranges float64, n int) float64 {
+	if n < 2 {
+		return 0
+	}
+	return ranges / float64(n-1) / D2
+}
@@ -0,0 +1,55 @@
+package movingAverage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMovingRange(t *testing.T) {
+	assert := assert.New(t)
+
+	add := NewMovingRange(3)
+	a, b := add(1)
+	assert.Equal(1.0, a)
+	assert.Equal(0.0, b)
+
+	a, b = add(3)
+	t.Logf("|3-1|         / 1 / d2 = %g", b)
+	assert.Equal(2.0, a)
+	assert.InDelta(2/D2, b, 1e-12)
+
+	a, b = add(2)
+	t.Logf("(2+1)         / 2 / d2 = %g", b)
+	assert.Equal(2.0, a)
+	assert.InDelta(1.5/D2, b, 1e-12)
+
+	a, b = add(8)
+	t.Logf("(1+6)         / 2 / d2 = %g", b) // 3, 2, 8
+	assert.InDelta(13.0/3, a, 1e-12)
+	assert.InDelta(3.5/D2, b, 1e-12)
+
+	a, b = add(8)
+	t.Logf("(6+0)         / 2 / d2 = %g", b) // 2, 8, 8
+	assert.Equal(6.0, a)
+	assert.InDelta(3/D2, b, 1e-12)
+}
+
+// TestMovingRangeShift shows a step inflates the sd, but barely moves
+// the moving range.
+func TestMovingRangeShift(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []float64{10, 11, 10, 11, 10, 11, 20, 21, 20, 21, 20, 21}
+	mr, simple := NewMovingRange(12), New(12)
+	cumulative := CumulativeMovingRange()
+	var sigma, sd, c float64
+	for _, x := range data {
+		_, sigma = mr(x)
+		_, sd = simple(x)
+		_, c = cumulative(x)
+	}
+	t.Logf("moving range sigma %g, sd %g", sigma, sd)
+	assert.InDelta((10*1+9)/11.0/D2, sigma, 1e-12)
+	assert.Equal(sigma, c)
+	assert.True(sd > 2*sigma)
+}
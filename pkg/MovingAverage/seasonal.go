@@ -0,0 +1,86 @@
+package movingAverage
+
+import (
+	"time"
+)
+
+/*
+ * Seasonal -- compares each sample to the samples from the same time of day,
+ * or of the week, rather than to the ones just before it. The period is cut
+ * into slots, such as the 144 ten-minute slots of a day, and each slot gets
+ * its own moving average, so 10:20 today is compared with 10:20 on the last
+ * few days. Daily and weekly cycles then show up as differences between the
+ * slots, instead of as anomalies.
+*
+*/
+
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+// Seasonal keeps a moving average for each slot of a period, created
+// on demand by the function it's given, such as
+// func() func(s float64) (float64, float64) { return New(7) }
+type Seasonal struct {
+	Period time.Duration // Day or Week
+	Width  time.Duration // of a slot, such as 10 minutes
+
+	newAverage func() func(s float64) (float64, float64)
+	slots      map[int64]*slot
+}
+
+// slot is the moving average for one time of day or week, and
+// what it last returned.
+type slot struct {
+	add     func(s float64) (float64, float64)
+	average float64
+	sd      float64
+	n       int
+}
+
+// NewSeasonal creates a seasonal average over a period, in slots of width.
+func NewSeasonal(period, width time.Duration, newAverage func() func(s float64) (float64, float64)) *Seasonal {
+	return &Seasonal{
+		Period:     period,
+		Width:      width,
+		newAverage: newAverage,
+		slots:      make(map[int64]*slot),
+	}
+}
+
+// Stats returns the average and sd for the slot a time falls in, and
+// how many samples have gone into that slot, on past days or weeks,
+// without adding anything to it.
+func (s *Seasonal) Stats(t time.Time) (float64, float64, int) {
+	sl, ok := s.slots[s.slot(t)]
+	if !ok {
+		return 0, 0, 0
+	}
+	return sl.average, sl.sd, sl.n
+}
+
+// Add adds a sample to the slot its time falls in, returning the
+// slot's new average and sd.
+func (s *Seasonal) Add(t time.Time, new float64) (float64, float64) {
+	i := s.slot(t)
+	sl, ok := s.slots[i]
+	if !ok {
+		sl = &slot{add: s.newAverage()}
+		s.slots[i] = sl
+	}
+	sl.average, sl.sd = sl.add(new)
+	sl.n++
+	return sl.average, sl.sd
+}
+
+// slot finds which slot of the period a time falls in, using the
+// time of day in its own location, plus the day of the week if
+// the period is longer than a day.
+func (s *Seasonal) slot(t time.Time) int64 {
+	hour, min, sec := t.Clock()
+	offset := time.Duration(t.Weekday())*Day +
+		time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+
+	return int64((offset % s.Period) / s.Width)
+}
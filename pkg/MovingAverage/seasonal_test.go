@@ -0,0 +1,53 @@
+package movingAverage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSeasonal(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewSeasonal(Day, 10*time.Minute, func() func(s float64) (float64, float64) {
+		return New(3)
+	})
+	monday := time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	// busy at 10:20, quiet at 03:00, for three days
+	for day := 0; day < 3; day++ {
+		today := monday.AddDate(0, 0, day)
+		s.Add(today.Add(10*time.Hour+20*time.Minute), 100+float64(day))
+		s.Add(today.Add(3*time.Hour), 10)
+	}
+	a, b, n := s.Stats(monday.Add(10*time.Hour + 25*time.Minute)) // the same slot
+	assert.Equal(101.0, a)
+	assert.Equal(1.0, b)
+	assert.Equal(3, n)
+
+	a, b, n = s.Stats(monday.Add(3 * time.Hour))
+	assert.Equal(10.0, a)
+	assert.Equal(0.0, b)
+	assert.Equal(3, n)
+
+	_, _, n = s.Stats(monday.Add(10 * time.Hour)) // never seen
+	assert.Equal(0, n)
+}
+
+func TestSeasonalWeek(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewSeasonal(Week, time.Hour, func() func(s float64) (float64, float64) {
+		return New(3)
+	})
+	monday := time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC)
+
+	s.Add(monday, 100)
+	s.Add(monday.AddDate(0, 0, 5), 5) // saturday, at the same time
+	a, _, n := s.Stats(monday.AddDate(0, 0, 7))
+	assert.Equal(100.0, a)
+	assert.Equal(1, n)
+	a, _, n = s.Stats(monday.AddDate(0, 0, 12))
+	assert.Equal(5.0, a)
+	assert.Equal(1, n)
+}
@@ -0,0 +1,81 @@
+package movingAverage
+
+/*
+ * Sliding window -- the same simple moving average and standard deviation
+ * as New, but updated in constant time: the new sample replaces the oldest,
+ * and the mean and accumulator are adjusted for the pair, rather than
+ * re-walking every bin. For a window of N, replacing old with new,
+
+	oldMean := M
+	M := M + (new-old)/N
+	S := S + (new-old)*(new-M + old-oldMean)
+
+ * which is the windowed form of Knuth-Welford, and stays stable where the
+ * textbook sum-of-squares form would not. Until the window is full, samples
+ * are just added, as in Cumulative. Rounding errors still accumulate
+ * slowly, so each time the window has been completely replaced, we recompute
+ * from the bins, which costs one walk of them every nSamples calls.
+*
+*/
+
+// NewSliding takes a number of samples to consider and generates
+// an "add" function like New's, which costs O(1) per sample instead
+// of O(nSamples).
+func NewSliding(nSamples int) func(s float64) (float64, float64) {
+	return NewSlidingWindow(nSamples).Add
+}
+
+// SlidingWindow is NewSliding's window of the last nSamples, whose Mean
+// and S are adjusted as each sample slides in and the oldest slides out.
+// Once it's Ready, its Count stays at nSamples.
+type SlidingWindow struct {
+	window
+	Mean float64
+	S    float64 // the accumulator for the variance and SD
+}
+
+// NewSlidingWindow creates a sliding moving average of nSamples.
+func NewSlidingWindow(nSamples int) *SlidingWindow {
+	return &SlidingWindow{window: window{bins: make([]float64, nSamples)}}
+}
+
+// Add replaces the oldest value with the new one, and adjusts the
+// mean and standard deviation to suit.
+func (w *SlidingWindow) Add(new float64) (float64, float64) {
+	old, full := w.push(new)
+
+	switch {
+	case !full:
+		// still filling, so just add it
+		oldMean := w.Mean
+		w.Mean = w.Mean + (new-w.Mean)/float64(w.n)
+		w.S = w.S + (new-w.Mean)*(new-oldMean)
+	case w.i == 0:
+		// we've been all the way round, so start afresh
+		w.Mean, w.S = walk(w.bins)
+	default:
+		oldMean := w.Mean
+		w.Mean = w.Mean + (new-old)/float64(w.n)
+		w.S = w.S + (new-old)*(new-w.Mean+old-oldMean)
+	}
+	if w.S < 0 {
+		// rounding, when the variance is near zero
+		w.S = 0
+	}
+
+	// return the mean and SD
+	return w.Mean, sd(w.S, w.n)
+}
+
+// walk iterates across the bins, getting a mean and the
+// accumulator for the variance, as New does.
+func walk(bins []float64) (float64, float64) {
+	var Mean, S float64
+
+	for k, x := range bins {
+		oldMean := Mean
+		Mean = Mean + (x-Mean)/float64(k+1)
+		S = S + (x-Mean)*(x-oldMean)
+	}
+	return Mean, S
+}
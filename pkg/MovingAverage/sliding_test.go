@@ -0,0 +1,71 @@
+package movingAverage
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+// TestSliding checks the sliding window agrees with New, on the
+// same samples as TestMovingAverage and on a long random walk.
+func TestSliding(t *testing.T) {
+	assert := assert.New(t)
+
+	slow, fast := New(5), NewSliding(5)
+	for _, x := range []float64{1, 2, 3, 4, 5, 9, 3, 0, -9, -8} {
+		a, b := slow(x)
+		c, d := fast(x)
+		assert.InDelta(a, c, 1e-12)
+		assert.InDelta(b, d, 1e-12)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	slow, fast = New(13), NewSliding(13)
+	x := 1e6
+	for i := 0; i < 100000; i++ {
+		x += r.NormFloat64() * 1000
+		a, b := slow(x)
+		c, d := fast(x)
+		if i%1000 == 0 {
+			assert.InDelta(a, c, 1e-6, "mean at %d", i)
+			assert.InDelta(b, d, 1e-6, "sd at %d", i)
+		}
+	}
+}
+
+// TestSlidingConstant checks a constant input has no deviation,
+// rather than the square root of a rounding error.
+func TestSlidingConstant(t *testing.T) {
+	assert := assert.New(t)
+
+	add := NewSliding(7)
+	var a, b float64
+	for i := 0; i < 30; i++ {
+		a, b = add(0.1)
+	}
+	assert.InDelta(0.1, a, 1e-15)
+	assert.InDelta(0.0, b, 1e-7)
+}
+
+// BenchmarkAverages compares the loop in New with the sliding
+// window, for windows from the default up to two hours of
+// one-second samples.
+func BenchmarkAverages(b *testing.B) {
+	for _, n := range []int{5, 13, 720, 7200} {
+		for _, avg := range []struct {
+			name string
+			new  func(int) func(float64) (float64, float64)
+		}{
+			{"New", New},
+			{"NewSliding", NewSliding},
+		} {
+			b.Run(fmt.Sprintf("%s/%d", avg.name, n), func(b *testing.B) {
+				add := avg.new(n)
+				for i := 0; i < b.N; i++ {
+					add(float64(i % 1000))
+				}
+			})
+		}
+	}
+}
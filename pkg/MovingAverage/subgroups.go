@@ -0,0 +1,141 @@
+package movingAverage
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+ * Subgroups -- the X-bar chart's way of averaging. Rather than each sample,
+ * we look at the means of subgroups of n consecutive samples, which are far
+ * closer to normal, and estimate their sigma from the spread within the
+ * subgroups, either the range R or the standard deviation S:
+
+	centre := mean(means)
+	sigma := A2 * mean(R) / 3      or     A3 * mean(S) / 3
+
+ * so the 3-sigma limits are the textbook centre +/- A2 * mean(R). The spreads
+ * get their own chart, with limits of D3 and D4 times mean(R), or B3 and B4
+ * times mean(S). The constants are from Montgomery, "Introduction to
+ * Statistical Quality Control", appendix VI.
+*
+*/
+
+// Constants are the control-chart factors for a subgroup size.
+type Constants struct {
+	A2, A3, D2, D3, D4, B3, B4, C4 float64
+}
+
+// constants are indexed by subgroup size, from 2 to 25.
+var constants = []Constants{
+	2:  {A2: 1.880, A3: 2.659, D2: 1.128, D3: 0, D4: 3.267, B3: 0, B4: 3.267, C4: 0.7979},
+	3:  {A2: 1.023, A3: 1.954, D2: 1.693, D3: 0, D4: 2.574, B3: 0, B4: 2.568, C4: 0.8862},
+	4:  {A2: 0.729, A3: 1.628, D2: 2.059, D3: 0, D4: 2.282, B3: 0, B4: 2.266, C4: 0.9213},
+	5:  {A2: 0.577, A3: 1.427, D2: 2.326, D3: 0, D4: 2.114, B3: 0, B4: 2.089, C4: 0.9400},
+	6:  {A2: 0.483, A3: 1.287, D2: 2.534, D3: 0, D4: 2.004, B3: 0.030, B4: 1.970, C4: 0.9515},
+	7:  {A2: 0.419, A3: 1.182, D2: 2.704, D3: 0.076, D4: 1.924, B3: 0.118, B4: 1.882, C4: 0.9594},
+	8:  {A2: 0.373, A3: 1.099, D2: 2.847, D3: 0.136, D4: 1.864, B3: 0.185, B4: 1.815, C4: 0.9650},
+	9:  {A2: 0.337, A3: 1.032, D2: 2.970, D3: 0.184, D4: 1.816, B3: 0.239, B4: 1.761, C4: 0.9693},
+	10: {A2: 0.308, A3: 0.975, D2: 3.078, D3: 0.223, D4: 1.777, B3: 0.284, B4: 1.716, C4: 0.9727},
+	11: {A2: 0.285, A3: 0.927, D2: 3.173, D3: 0.256, D4: 1.744, B3: 0.321, B4: 1.679, C4: 0.9754},
+	12: {A2: 0.266, A3: 0.886, D2: 3.258, D3: 0.283, D4: 1.717, B3: 0.354, B4: 1.646, C4: 0.9776},
+	13: {A2: 0.249, A3: 0.850, D2: 3.336, D3: 0.307, D4: 1.693, B3: 0.382, B4: 1.618, C4: 0.9794},
+	14: {A2: 0.235, A3: 0.817, D2: 3.407, D3: 0.328, D4: 1.672, B3: 0.406, B4: 1.594, C4: 0.9810},
+	15: {A2: 0.223, A3: 0.789, D2: 3.472, D3: 0.347, D4: 1.653, B3: 0.428, B4: 1.572, C4: 0.9823},
+	16: {A2: 0.212, A3: 0.763, D2: 3.532, D3: 0.363, D4: 1.637, B3: 0.448, B4: 1.552, C4: 0.9835},
+	17: {A2: 0.203, A3: 0.739, D2: 3.588, D3: 0.378, D4: 1.622, B3: 0.466, B4: 1.534, C4: 0.9845},
+	18: {A2: 0.194, A3: 0.718, D2: 3.640, D3: 0.391, D4: 1.608, B3: 0.482, B4: 1.518, C4: 0.9854},
+	19: {A2: 0.187, A3: 0.698, D2: 3.689, D3: 0.403, D4: 1.597, B3: 0.497, B4: 1.503, C4: 0.9862},
+	20: {A2: 0.180, A3: 0.680, D2: 3.735, D3: 0.415, D4: 1.585, B3: 0.510, B4: 1.490, C4: 0.9869},
+	21: {A2: 0.173, A3: 0.663, D2: 3.778, D3: 0.425, D4: 1.575, B3: 0.523, B4: 1.477, C4: 0.9876},
+	22: {A2: 0.167, A3: 0.647, D2: 3.819, D3: 0.434, D4: 1.566, B3: 0.534, B4: 1.466, C4: 0.9882},
+	23: {A2: 0.162, A3: 0.633, D2: 3.858, D3: 0.443, D4: 1.557, B3: 0.545, B4: 1.455, C4: 0.9887},
+	24: {A2: 0.157, A3: 0.619, D2: 3.895, D3: 0.451, D4: 1.548, B3: 0.555, B4: 1.445, C4: 0.9892},
+	25: {A2: 0.153, A3: 0.606, D2: 3.931, D3: 0.459, D4: 1.541, B3: 0.565, B4: 1.435, C4: 0.9896},
+}
+
+// ConstantsFor returns the constants for a subgroup of n samples.
+func ConstantsFor(n int) (Constants, error) {
+	if n < 2 || n >= len(constants) {
+		return Constants{}, fmt.Errorf("subgroups must have from 2 to %d samples, not %d", len(constants)-1, n)
+	}
+	return constants[n], nil
+}
+
+// Subgroup summarizes a subgroup of samples.
+type Subgroup struct {
+	Mean  float64
+	Range float64 // the largest less the smallest
+	SD    float64
+	N     int
+}
+
+// Summarize finds the mean, range and standard deviation of a subgroup.
+func Summarize(samples []float64) Subgroup {
+	g := Subgroup{N: len(samples)}
+	if g.N == 0 {
+		return g
+	}
+	Mean, S := walk(samples)
+	lo, hi := samples[0], samples[0]
+	for _, x := range samples {
+		lo, hi = math.Min(lo, x), math.Max(hi, x)
+	}
+	g.Mean, g.Range, g.SD = Mean, hi-lo, sd(S, g.N)
+	return g
+}
+
+// XBar averages the means of subgroups, and their ranges or standard
+// deviations, using a pair of "add" functions such as New's or
+// Cumulative's, one for each.
+type XBar struct {
+	Constants
+	Size int
+	S    bool // use the standard deviations, not the ranges
+
+	means   func(s float64) (float64, float64)
+	spreads func(s float64) (float64, float64)
+}
+
+// NewXBar creates an X-bar average for subgroups of size, with the
+// ranges or, if s is true, the standard deviations.
+func NewXBar(size int, s bool, means, spreads func(s float64) (float64, float64)) (*XBar, error) {
+	c, err := ConstantsFor(size)
+	if err != nil {
+		return nil, err
+	}
+	return &XBar{Constants: c, Size: size, S: s, means: means, spreads: spreads}, nil
+}
+
+// Spread returns the range or standard deviation of a subgroup,
+// whichever we're using.
+func (x *XBar) Spread(g Subgroup) float64 {
+	if x.S {
+		return g.SD
+	}
+	return g.Range
+}
+
+// Add adds a subgroup, and returns the centre line and the sigma of
+// the subgroup means.
+func (x *XBar) Add(g Subgroup) (float64, float64) {
+	centre, _ := x.means(g.Mean)
+	spread, _ := x.spreads(x.Spread(g))
+
+	// return the centre and the sigma
+	if x.S {
+		return centre, x.A3 * spread / 3
+	}
+	return centre, x.A2 * spread / 3
+}
+
+// SpreadLimits turns the sigma of the means back into the average
+// spread, and returns it with the spread chart's lower and upper limits.
+func (x *XBar) SpreadLimits(sigma float64) (float64, float64, float64) {
+	if x.S {
+		bar := 3 * sigma / x.A3
+		return bar, x.B3 * bar, x.B4 * bar
+	}
+	bar := 3 * sigma / x.A2
+	return bar, x.D3 * bar, x.D4 * bar
+}
@@ -0,0 +1,51 @@
+package movingAverage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	assert := assert.New(t)
+
+	g := Summarize([]float64{3, 1, 5, 2, 4})
+	assert.Equal(3.0, g.Mean)
+	assert.Equal(4.0, g.Range)
+	assert.InDelta(1.5811388300841898, g.SD, 1e-12)
+	assert.Equal(5, g.N)
+}
+
+func TestXBar(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name  string
+		s     bool
+		sigma float64
+		bar   float64
+		lower float64
+		upper float64
+	}{
+		{name: "range", sigma: 0.577 * 4 / 3, bar: 4, upper: 2.114 * 4},
+		{name: "sd", s: true, sigma: 1.427 * 1.5811388300841898 / 3,
+			bar: 1.5811388300841898, upper: 2.089 * 1.5811388300841898},
+	}
+	for _, tt := range tests {
+		x, err := NewXBar(5, tt.s, Cumulative(), Cumulative())
+		assert.Equal(nil, err)
+		x.Add(Summarize([]float64{1, 2, 3, 4, 5}))
+		centre, sigma := x.Add(Summarize([]float64{2, 3, 4, 5, 6}))
+		assert.Equal(3.5, centre, tt.name)
+		assert.InDelta(tt.sigma, sigma, 1e-12, tt.name)
+
+		bar, lower, upper := x.SpreadLimits(sigma)
+		assert.InDelta(tt.bar, bar, 1e-12, tt.name)
+		assert.InDelta(tt.lower, lower, 1e-12, tt.name)
+		assert.InDelta(tt.upper, upper, 1e-12, tt.name)
+	}
+
+	for _, n := range []int{1, 26} {
+		_, err := NewXBar(n, false, Cumulative(), Cumulative())
+		assert.True(err != nil, n)
+	}
+}